@@ -0,0 +1,456 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	mapperImport = "github.com/catalystcommunity/mapper/pkg"
+	inPrefix     = "in="
+)
+
+// unsupportedOptions are the mapper tag options generated code doesn't implement
+var unsupportedOptions = []string{
+	"strict", "required", "readonly", "writeonly", "move", "copy", "remain",
+	"layout=", "tz=", "default=", "transform=", "out=",
+}
+
+// mappedStruct is a struct type that gets generated methods
+type mappedStruct struct {
+	Name   string
+	Fields []mappedField
+	// Nested fields hold values with mapper tags or hooks of their own, they are mapped by the Mapper
+	Nested []nestedField
+}
+
+// mappedField is a field with a mapper tag
+type mappedField struct {
+	Name string
+	// Key is the field's json key escaped for gjson, Path its mapped path
+	Key       string
+	Path      string
+	AsString  bool
+	OmitEmpty bool
+	// Coercion converts a gjson.Result named result to the field's type, nil when the field isn't coerced
+	Coercion *coercion
+}
+
+type nestedField struct {
+	Name string
+	Key  string
+}
+
+// coercion is how a coerced field turns a gjson.Result into json
+type coercion struct {
+	// Expr is the value to encode, Format the strconv function that encodes it or empty for json.Marshal
+	Expr   string
+	Format string
+}
+
+// collectStructs finds the named struct types, or every struct with mapper tags when names is empty
+func collectStructs(pkg *types.Package, names []string) ([]mappedStruct, error) {
+	if len(names) == 0 {
+		for _, name := range pkg.Scope().Names() {
+			if named, ok := structType(pkg, name); ok && hasMapperTags(named) {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no structs with mapper tags in %s", pkg.Name())
+		}
+	}
+	structs := []mappedStruct{}
+	for _, name := range names {
+		named, ok := structType(pkg, name)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a struct type of %s", name, pkg.Name())
+		}
+		mapped, err := analyzeStruct(name, named)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		structs = append(structs, mapped)
+	}
+	sort.Slice(structs, func(i, j int) bool { return structs[i].Name < structs[j].Name })
+	return structs, nil
+}
+
+func structType(pkg *types.Package, name string) (*types.Named, bool) {
+	typeName, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok || typeName.IsAlias() {
+		return nil, false
+	}
+	named, ok := typeName.Type().(*types.Named)
+	if !ok {
+		return nil, false
+	}
+	_, ok = named.Underlying().(*types.Struct)
+	return named, ok
+}
+
+func hasMapperTags(named *types.Named) bool {
+	fields := named.Underlying().(*types.Struct)
+	for i := 0; i < fields.NumFields(); i++ {
+		if reflect.StructTag(fields.Tag(i)).Get("mapper") != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzeStruct works out what the generated methods of a struct do, the same way the Mapper's plan does
+func analyzeStruct(name string, named *types.Named) (mappedStruct, error) {
+	mapped := mappedStruct{Name: name}
+	if hasMethod(named, "MarshalJSON", false) || hasMethod(named, "UnmarshalJSON", true) {
+		return mapped, fmt.Errorf("types with their own json encoding aren't mapped")
+	}
+	if hasMethod(named, "MarshalMapper", true) || hasMethod(named, "UnmarshalMapper", true) {
+		return mapped, fmt.Errorf("types that map themselves don't need generated code")
+	}
+	fields := named.Underlying().(*types.Struct)
+	for i := 0; i < fields.NumFields(); i++ {
+		field := fields.Field(i)
+		tag := reflect.StructTag(fields.Tag(i))
+		if field.Embedded() {
+			return mapped, fmt.Errorf("embedded field %s isn't supported by generated code", field.Name())
+		}
+		jsonTag := tag.Get("json")
+		if !field.Exported() || jsonTag == "-" {
+			continue
+		}
+		if types.Typ[types.Invalid] == field.Type() {
+			return mapped, fmt.Errorf("field %s has an invalid type", field.Name())
+		}
+		jsonName, _, _ := strings.Cut(jsonTag, ",")
+		if jsonName == "" {
+			jsonName = field.Name()
+		}
+		key := escapePathKey(jsonName)
+		mapperTag := tag.Get("mapper")
+		if mapperTag == "" {
+			if needsMapping(field.Type(), map[types.Type]bool{}) {
+				mapped.Nested = append(mapped.Nested, nestedField{Name: field.Name(), Key: key})
+			}
+			continue
+		}
+		if needsMapping(field.Type(), map[types.Type]bool{}) {
+			return mapped, fmt.Errorf("field %s has a mapper tag and holds values that need mapping themselves, which generated code doesn't support", field.Name())
+		}
+		mappedField, err := analyzeField(field, mapperTag, key)
+		if err != nil {
+			return mapped, fmt.Errorf("field %s: %w", field.Name(), err)
+		}
+		mapped.Fields = append(mapped.Fields, mappedField)
+	}
+	if len(mapped.Fields) == 0 && len(mapped.Nested) == 0 {
+		return mapped, fmt.Errorf("nothing to map")
+	}
+	return mapped, nil
+}
+
+// analyzeField reads a mapper tag the way the Mapper does, rejecting the options generated code can't do
+func analyzeField(field *types.Var, mapperTag, key string) (mappedField, error) {
+	mapped := mappedField{Name: field.Name(), Key: key}
+	coerce := false
+	for _, part := range strings.Split(mapperTag, ",") {
+		switch {
+		case part == "string":
+			mapped.AsString = true
+		case part == "omitempty":
+			mapped.OmitEmpty = true
+		case part == "coerce":
+			coerce = true
+		case isUnsupported(part):
+			return mapped, fmt.Errorf("option %s isn't supported by generated code", part)
+		default:
+			mapped.Path = strings.TrimPrefix(part, inPrefix)
+		}
+	}
+	if mapped.Path == "" {
+		mapped.Path = key
+	}
+	if err := checkPath(mapped.Path); err != nil {
+		return mapped, err
+	}
+	if coerce {
+		c, err := coercionFor(field.Type())
+		if err != nil {
+			return mapped, err
+		}
+		mapped.Coercion = c
+	}
+	return mapped, nil
+}
+
+func isUnsupported(part string) bool {
+	for _, option := range unsupportedOptions {
+		if part == option || strings.HasSuffix(option, "=") && strings.HasPrefix(part, option) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPath accepts paths made of object keys and array indexes, the ones gjson reads and sjson writes the same way
+func checkPath(path string) error {
+	for _, component := range splitPath(path) {
+		key := unescapePathKey(component)
+		if key == "" || key == "-1" || escapePathKey(key) != component {
+			return fmt.Errorf("path %s uses path syntax that isn't supported by generated code", path)
+		}
+	}
+	return nil
+}
+
+// coercionFor returns how coerce converts a value for typ, the way the Mapper's lenient coercion does
+func coercionFor(typ types.Type) (*coercion, error) {
+	if pointer, ok := typ.Underlying().(*types.Pointer); ok {
+		typ = pointer.Elem()
+	}
+	if named, ok := typ.(*types.Named); ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "time" {
+		return nil, fmt.Errorf("coerce of %s isn't supported by generated code", typ)
+	}
+	basic, ok := typ.Underlying().(*types.Basic)
+	if !ok {
+		return nil, fmt.Errorf("coerce is only generated for strings, bools and numbers, not %s", typ)
+	}
+	switch basic.Kind() {
+	case types.String:
+		return &coercion{Expr: "result.String()"}, nil
+	case types.Bool:
+		return &coercion{Expr: "result.Bool()", Format: "strconv.FormatBool(%s)"}, nil
+	case types.Int64:
+		return &coercion{Expr: "result.Int()", Format: "strconv.FormatInt(%s, 10)"}, nil
+	case types.Int, types.Int8, types.Int16, types.Int32:
+		return &coercion{Expr: fmt.Sprintf("int64(%s(result.Int()))", basic.Name()), Format: "strconv.FormatInt(%s, 10)"}, nil
+	case types.Uint64:
+		return &coercion{Expr: "result.Uint()", Format: "strconv.FormatUint(%s, 10)"}, nil
+	case types.Uint, types.Uint8, types.Uint16, types.Uint32:
+		return &coercion{Expr: fmt.Sprintf("uint64(%s(result.Uint()))", basic.Name()), Format: "strconv.FormatUint(%s, 10)"}, nil
+	case types.Float32:
+		return &coercion{Expr: "float32(result.Float())"}, nil
+	case types.Float64:
+		return &coercion{Expr: "result.Float()"}, nil
+	}
+	return nil, fmt.Errorf("coerce is only generated for strings, bools and numbers, not %s", typ)
+}
+
+// needsMapping reports whether values of typ have mapper tags or hooks anywhere inside them, like the Mapper's typeNeedsMapping
+func needsMapping(typ types.Type, visiting map[types.Type]bool) bool {
+	if hasHooks(typ) {
+		return true
+	}
+	if hasMethod(typ, "MarshalJSON", false) || hasMethod(typ, "UnmarshalJSON", true) {
+		return false
+	}
+	switch underlying := typ.Underlying().(type) {
+	case *types.Interface:
+		return true
+	case *types.Pointer:
+		return needsMapping(underlying.Elem(), visiting)
+	case *types.Slice:
+		return needsMapping(underlying.Elem(), visiting)
+	case *types.Array:
+		return needsMapping(underlying.Elem(), visiting)
+	case *types.Map:
+		return needsMapping(underlying.Elem(), visiting)
+	case *types.Struct:
+		if visiting[typ] {
+			return false
+		}
+		visiting[typ] = true
+		for i := 0; i < underlying.NumFields(); i++ {
+			field := underlying.Field(i)
+			tag := reflect.StructTag(underlying.Tag(i))
+			if !field.Exported() && !field.Embedded() || tag.Get("json") == "-" {
+				continue
+			}
+			if tag.Get("mapper") != "" || needsMapping(field.Type(), visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasHooks reports whether the Mapper calls methods of typ while mapping it
+func hasHooks(typ types.Type) bool {
+	for _, name := range []string{"MarshalMapper", "UnmarshalMapper", "BeforeMap", "AfterMap"} {
+		if hasMethod(typ, name, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMethod reports whether typ, or a pointer to it when addressable is set, has the named method
+func hasMethod(typ types.Type, name string, addressable bool) bool {
+	object, _, _ := types.LookupFieldOrMethod(typ, addressable, nil, name)
+	_, ok := object.(*types.Func)
+	return ok
+}
+
+// generate writes the source of the generated file
+func generate(pkgName string, structs []mappedStruct) []byte {
+	var body bytes.Buffer
+	usesStrconv, usesSjson := false, false
+	for _, mapped := range structs {
+		writeMarshal(&body, mapped)
+		writeUnmarshal(&body, mapped)
+		usesSjson = usesSjson || len(mapped.Fields) > 0 || len(mapped.Nested) > 0
+		for _, field := range mapped.Fields {
+			usesStrconv = usesStrconv || field.Coercion != nil && field.Coercion.Format != ""
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by mappergen; DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkgName)
+	fmt.Fprintf(&out, "\t\"encoding/json\"\n")
+	fmt.Fprintf(&out, "\tmapper %q\n", mapperImport)
+	fmt.Fprintf(&out, "\t\"github.com/tidwall/gjson\"\n")
+	if usesSjson {
+		fmt.Fprintf(&out, "\t\"github.com/tidwall/sjson\"\n")
+	}
+	if usesStrconv {
+		fmt.Fprintf(&out, "\t\"strconv\"\n")
+	}
+	fmt.Fprintf(&out, ")\n")
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+// writeMarshal writes MarshalMapped, it follows marshalStruct step by step
+func writeMarshal(w *bytes.Buffer, mapped mappedStruct) {
+	fmt.Fprintf(w, "\n// MarshalMapped returns the json encoding of v with every mapper tagged field written to its mapped path\n")
+	fmt.Fprintf(w, "func (v %s) MarshalMapped(m *mapper.Mapper) ([]byte, error) {\n", mapped.Name)
+	fmt.Fprintf(w, "data, err := json.Marshal(v)\nif err != nil {\nreturn nil, err\n}\n")
+	for _, nested := range mapped.Nested {
+		fmt.Fprintf(w, "if gjson.GetBytes(data, %q).Exists() {\n", nested.Key)
+		fmt.Fprintf(w, "nested, err := m.Marshal(v.%s)\nif err == nil {\ndata, err = sjson.SetRawBytes(data, %q, nested)\n}\n", nested.Name, nested.Key)
+		fmt.Fprintf(w, "if err != nil {\nreturn nil, mapper.GeneratedNestedError(err, %q, %q)\n}\n}\n", nested.Name, nested.Key)
+	}
+	if len(mapped.Fields) > 0 {
+		fmt.Fprintf(w, "changes := make([][]byte, %d)\n", len(mapped.Fields))
+	}
+	for i, field := range mapped.Fields {
+		fieldErr := fmt.Sprintf("mapper.GeneratedFieldError(err, %q, %q, %q)", field.Name, field.Path, field.Path)
+		fmt.Fprintf(w, "if result := gjson.GetBytes(data, %q); result.Exists() {\n", field.Key)
+		writeValue(w, field, "return nil, "+fieldErr)
+		if field.OmitEmpty {
+			fmt.Fprintf(w, "if value == \"\" {\n")
+			fmt.Fprintf(w, "if data, err = sjson.DeleteBytes(data, %q); err != nil {\n", field.Key)
+			fmt.Fprintf(w, "return nil, mapper.GeneratedFieldError(err, %q, %q, %q)\n}\n", field.Name, field.Path, field.Key)
+			fmt.Fprintf(w, "} else {\nchanges[%d] = []byte(value)\n}\n", i)
+		} else {
+			fmt.Fprintf(w, "changes[%d] = []byte(value)\n", i)
+		}
+		fmt.Fprintf(w, "}\n")
+	}
+	for i, field := range mapped.Fields {
+		fmt.Fprintf(w, "if changes[%d] != nil {\n", i)
+		fmt.Fprintf(w, "if data, err = sjson.SetRawBytes(data, %q, changes[%d]); err != nil {\n", field.Path, i)
+		fmt.Fprintf(w, "return nil, mapper.GeneratedFieldError(err, %q, %q, %q)\n}\n}\n", field.Name, field.Path, field.Path)
+	}
+	fmt.Fprintf(w, "return data, nil\n}\n")
+}
+
+// writeUnmarshal writes UnmarshalMapped, it follows unmarshalStruct step by step
+func writeUnmarshal(w *bytes.Buffer, mapped mappedStruct) {
+	fmt.Fprintf(w, "\n// UnmarshalMapped parses data into v, reading every mapper tagged field from its mapped path\n")
+	fmt.Fprintf(w, "func (v *%s) UnmarshalMapped(m *mapper.Mapper, data []byte) error {\n", mapped.Name)
+	fmt.Fprintf(w, "var err error\n")
+	if len(mapped.Fields) > 0 {
+		fmt.Fprintf(w, "changes := make([][]byte, %d)\n", len(mapped.Fields))
+	}
+	for i, field := range mapped.Fields {
+		fieldErr := fmt.Sprintf("mapper.GeneratedFieldError(err, %q, %q, %q)", field.Name, field.Path, field.Path)
+		fmt.Fprintf(w, "if result := gjson.GetBytes(data, %q); result.Exists() {\n", field.Path)
+		writeValue(w, field, "return "+fieldErr)
+		if field.OmitEmpty {
+			fmt.Fprintf(w, "if value != \"\" {\nchanges[%d] = []byte(value)\n}\n", i)
+		} else {
+			fmt.Fprintf(w, "changes[%d] = []byte(value)\n", i)
+		}
+		fmt.Fprintf(w, "}\n")
+	}
+	for i, field := range mapped.Fields {
+		fmt.Fprintf(w, "if changes[%d] != nil {\n", i)
+		fmt.Fprintf(w, "if data, err = sjson.SetRawBytes(data, %q, changes[%d]); err != nil {\n", field.Key, i)
+		fmt.Fprintf(w, "return mapper.GeneratedFieldError(err, %q, %q, %q)\n}\n}\n", field.Name, field.Path, field.Key)
+	}
+	for i, nested := range mapped.Nested {
+		fmt.Fprintf(w, "nested%d := gjson.GetBytes(data, %q)\n", i, nested.Key)
+		fmt.Fprintf(w, "if nested%d.Exists() {\nif data, err = sjson.DeleteBytes(data, %q); err != nil {\nreturn err\n}\n}\n", i, nested.Key)
+	}
+	fmt.Fprintf(w, "if err = json.Unmarshal(data, v); err != nil {\nreturn mapper.GeneratedUnmarshalError(err, v)\n}\n")
+	for i, nested := range mapped.Nested {
+		fmt.Fprintf(w, "if nested%d.Exists() {\n", i)
+		fmt.Fprintf(w, "if err = m.Unmarshal([]byte(nested%d.Raw), &v.%s); err != nil {\n", i, nested.Name)
+		fmt.Fprintf(w, "return mapper.GeneratedNestedError(err, %q, %q)\n}\n}\n", nested.Name, nested.Key)
+	}
+	fmt.Fprintf(w, "return nil\n}\n")
+}
+
+// writeValue writes the statements that set value to the json written for a field, like getValue does
+func writeValue(w *bytes.Buffer, field mappedField, returnErr string) {
+	switch {
+	case field.Coercion != nil && field.Coercion.Format != "":
+		fmt.Fprintf(w, "value := "+field.Coercion.Format+"\n", field.Coercion.Expr)
+	case field.Coercion != nil:
+		fmt.Fprintf(w, "encoded, err := json.Marshal(%s)\nif err != nil {\n%s\n}\nvalue := string(encoded)\n", field.Coercion.Expr, returnErr)
+	case field.AsString:
+		fmt.Fprintf(w, "value := result.String()\n")
+	default:
+		fmt.Fprintf(w, "value := result.Raw\n")
+	}
+}
+
+// splitPath splits a path at the dots that aren't escaped
+func splitPath(path string) []string {
+	components := []string{}
+	start := 0
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '\\':
+			i++
+		case '.':
+			components = append(components, path[start:i])
+			start = i + 1
+		}
+	}
+	return append(components, path[start:])
+}
+
+// escapePathKey escapes a single object key so gjson and sjson don't read it as path syntax, like the Mapper does
+func escapePathKey(key string) string {
+	escaped := make([]byte, 0, len(key))
+	for i := 0; i < len(key); i++ {
+		if !isSafePathKeyChar(key[i]) {
+			escaped = append(escaped, '\\')
+		}
+		escaped = append(escaped, key[i])
+	}
+	return string(escaped)
+}
+
+func unescapePathKey(key string) string {
+	var builder strings.Builder
+	for i := 0; i < len(key); i++ {
+		if key[i] == '\\' && i+1 < len(key) {
+			i++
+		}
+		builder.WriteByte(key[i])
+	}
+	return builder.String()
+}
+
+func isSafePathKeyChar(c byte) bool {
+	return c <= ' ' || c > '~' || c == '_' || c == '-' || c == ':' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9')
+}
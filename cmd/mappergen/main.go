@@ -0,0 +1,98 @@
+// Command mappergen generates MarshalMapped and UnmarshalMapped methods for structs with mapper tags.
+// Marshal and Unmarshal call them instead of applying the tags with reflection. Run it with go generate:
+//
+//	//go:generate go run github.com/catalystcommunity/mapper/cmd/mappergen -type Order,Line
+//
+// Without -type every struct of the package with mapper tags gets methods. Generated code supports mapped
+// paths made of object keys and array indexes, and the coerce, string and omitempty options. Nested structs
+// with mapper tags of their own are mapped by the Mapper, so they work with or without generated code.
+// Types that need anything else, like transforms, defaults or embedded structs, are reported and keep
+// using reflection.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultOutput = "mapper_gen.go"
+
+func main() {
+	typeNames := flag.String("type", "", "comma separated list of the struct types to generate methods for, all structs with mapper tags if empty")
+	output := flag.String("output", defaultOutput, "the generated file, relative to the package directory")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: mappergen [-type T1,T2] [-output file] [directory]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	if err := run(dir, *typeNames, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "mappergen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, typeNames, output string) error {
+	if !filepath.IsAbs(output) {
+		output = filepath.Join(dir, output)
+	}
+	pkg, err := loadPackage(dir, output)
+	if err != nil {
+		return err
+	}
+	names := []string{}
+	if typeNames != "" {
+		names = strings.Split(typeNames, ",")
+	}
+	structs, err := collectStructs(pkg, names)
+	if err != nil {
+		return err
+	}
+	source, err := format.Source(generate(pkg.Name(), structs))
+	if err != nil {
+		return fmt.Errorf("formatting generated code: %w", err)
+	}
+	return os.WriteFile(output, source, 0o644)
+}
+
+// loadPackage type checks the package in dir, leaving out its tests and the file generated by a previous run
+func loadPackage(dir, output string) (*types.Package, error) {
+	buildPkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	files := []*ast.File{}
+	for _, name := range buildPkg.GoFiles {
+		path := filepath.Join(dir, name)
+		if filepath.Clean(path) == filepath.Clean(output) {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		// without the generated file the package may not compile, only the types of the mapped structs matter
+		Error: func(error) {},
+	}
+	pkg, _ := conf.Check(buildPkg.ImportPath, fset, files, nil)
+	return pkg, nil
+}
@@ -0,0 +1,583 @@
+package pkg
+
+import (
+	"encoding"
+	"encoding/json"
+	"github.com/tidwall/gjson"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// convertPlan copies one struct type into another for Convert without building the json document in between.
+// It works out at compile time which field of the source ends up at each key the destination reads, so it is only
+// used when that can be known up front: every field of both structs holds a plain value, and every path involved is
+// a plain key path. Anything else goes through Marshal and Unmarshal.
+type convertPlan struct {
+	// Direct is false when Convert has to take the json round trip
+	Direct  bool
+	Sources []convertSource
+	// Entries are the values the marshaled source would have, json keys first and then mapped paths in the order they are written
+	Entries []documentEntry
+	Targets []convertTarget
+}
+
+// documentEntry is a value in the marshaled source, written at a source field's json key or at its mapped path
+type documentEntry struct {
+	Path   []string
+	Source int
+}
+
+type convertSource struct {
+	Info tagInfo
+	// Mapped fields are written to their OutPath on marshal
+	Mapped bool
+	// OmitEmpty is the omitempty option of the json tag
+	OmitEmpty   bool
+	JSONEntry   int
+	MappedEntry int
+	// Needed is set when a target reads the field's json key, or the field has to be encoded to be checked
+	Needed bool
+}
+
+type convertTarget struct {
+	Info tagInfo
+	// Mapped fields are read from their mapped paths on unmarshal
+	Mapped bool
+	// Reads are the field's mapped paths, Key is its json key
+	Reads []pathRead
+	Key   pathRead
+}
+
+// pathRead is a path a target reads. Entries are written at the path, the ones written later first, and the first
+// one that is present is the value. Objects are written below the path, they would make the value an object.
+type pathRead struct {
+	Entries []int
+	Objects []int
+}
+
+// entryValue is the raw json of a documentEntry, Present is false when the source leaves it out
+type entryValue struct {
+	Raw     string
+	Present bool
+}
+
+// decodedScalar holds a value decoded for a target before any field is set
+type decodedScalar struct {
+	Target *convertTarget
+	Null   bool
+	Str    string
+	Bool   bool
+	Int    int64
+	Uint   uint64
+	Float  float64
+}
+
+type convertKey struct {
+	Source reflect.Type
+	Dest   reflect.Type
+	Tags   tagNames
+}
+
+var (
+	// convertCache holds a *convertPlan for every pair of types converted so far
+	convertCache sync.Map
+
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// getConvertPlan returns the cached plan for converting source into dest, compiling it on first use
+func getConvertPlan(source, dest reflect.Type, tags tagNames) *convertPlan {
+	key := convertKey{Source: source, Dest: dest, Tags: tags}
+	if cached, ok := convertCache.Load(key); ok {
+		return cached.(*convertPlan)
+	}
+	actual, _ := convertCache.LoadOrStore(key, compileConvertPlan(source, dest, tags))
+	return actual.(*convertPlan)
+}
+
+func compileConvertPlan(sourceType, destType reflect.Type, tags tagNames) *convertPlan {
+	indirect := &convertPlan{}
+	if tags.JSON != jsonTagName || hasCustomEncoding(sourceType) || hasCustomEncoding(destType) {
+		return indirect
+	}
+	sourcePlan := getTypePlan(sourceType, tags)
+	destPlan := getTypePlan(destType, tags)
+	if sourcePlan.Remain != nil || destPlan.Remain != nil {
+		return indirect
+	}
+
+	plan := &convertPlan{Direct: true}
+	mapped := []documentEntry{}
+	for i, field := range sourcePlan.Visible {
+		omit, ok := plainJSONOptions(field)
+		if !ok || !isPlainField(field) {
+			return indirect
+		}
+		source := convertSource{Info: field, OmitEmpty: omit, JSONEntry: len(plan.Entries), MappedEntry: -1}
+		plan.Entries = append(plan.Entries, documentEntry{Path: []string{field.JsonFieldName}, Source: i})
+		if field.MapperFieldPath != "" && !field.ReadOnly {
+			path, ok := plainPath(field.OutPath)
+			if !ok || field.OutPathError != nil {
+				return indirect
+			}
+			source.Mapped = true
+			source.Needed = true
+			source.MappedEntry = len(sourcePlan.Visible) + len(mapped)
+			mapped = append(mapped, documentEntry{Path: path, Source: i})
+		}
+		// encoding/json fails on floats it can't represent, they are always encoded to find out
+		source.Needed = source.Needed || isFloat(field.Field.Type)
+		plan.Sources = append(plan.Sources, source)
+	}
+	plan.Entries = append(plan.Entries, mapped...)
+
+	for _, field := range destPlan.Visible {
+		if _, ok := plainJSONOptions(field); !ok || !isPlainField(field) || embedsPointer(destType, field.Field.Index) {
+			return indirect
+		}
+		target := convertTarget{Info: field, Key: plan.readAt([]string{field.JsonFieldName})}
+		if field.MapperFieldPath != "" && !field.WriteOnly {
+			if field.PathError != nil || field.Projected {
+				return indirect
+			}
+			target.Mapped = true
+			for _, mapperPath := range append([]string{field.MapperFieldPath}, field.FallbackPaths...) {
+				path, ok := plainPath(mapperPath)
+				if !ok {
+					return indirect
+				}
+				target.Reads = append(target.Reads, plan.readAt(path))
+			}
+		}
+		plan.Targets = append(plan.Targets, target)
+	}
+
+	if !plan.unambiguous(destPlan) {
+		return indirect
+	}
+	for _, target := range plan.Targets {
+		for _, read := range append(target.Reads, target.Key) {
+			for _, entry := range append(read.Entries, read.Objects...) {
+				plan.Sources[plan.Entries[entry].Source].Needed = true
+			}
+		}
+	}
+	return plan
+}
+
+// readAt finds the entries at and below path. Entries above path hold plain values, so reading into them finds nothing.
+func (p *convertPlan) readAt(path []string) pathRead {
+	read := pathRead{}
+	for i := len(p.Entries) - 1; i >= 0; i-- {
+		if equalPath(p.Entries[i].Path, path) {
+			read.Entries = append(read.Entries, i)
+		} else if isStrictPrefix(path, p.Entries[i].Path) {
+			read.Objects = append(read.Objects, i)
+		}
+	}
+	return read
+}
+
+// unambiguous makes sure every entry is written on its own. Entries inside other entries, which sjson would have to
+// merge, are left to the json round trip, and so are keys that encoding/json would only match to a field ignoring case.
+func (p *convertPlan) unambiguous(destPlan *typePlan) bool {
+	for i, entry := range p.Entries {
+		for _, other := range p.Entries[i+1:] {
+			if isStrictPrefix(entry.Path, other.Path) || isStrictPrefix(other.Path, entry.Path) {
+				return false
+			}
+		}
+		for _, field := range destPlan.Visible {
+			if entry.Path[0] != field.JsonFieldName && strings.EqualFold(entry.Path[0], field.JsonFieldName) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// convertDirect copies source into dest using the convert plan of their types. It returns false, without touching
+// dest, when the plan doesn't apply or the values need anything the plan doesn't do, and Convert falls back to
+// the json round trip, which also produces the errors.
+func (m *Mapper) convertDirect(source, dest interface{}) bool {
+	if m.unknownFields != IgnoreUnknownFields || m.foldCase {
+		return false
+	}
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Ptr || destValue.IsNil() || destValue.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	sourceValue := reflect.ValueOf(source)
+	if sourceValue.Kind() == reflect.Ptr {
+		if sourceValue.IsNil() {
+			return false
+		}
+		sourceValue = sourceValue.Elem()
+	}
+	if sourceValue.Kind() != reflect.Struct {
+		return false
+	}
+	plan := getConvertPlan(sourceValue.Type(), destValue.Elem().Type(), m.tags)
+	if !plan.Direct {
+		return false
+	}
+	values, ok := m.readSources(plan, sourceValue)
+	if !ok {
+		return false
+	}
+	decoded, ok := m.decodeTargets(plan, values)
+	if !ok {
+		return false
+	}
+	setTargets(destValue.Elem(), decoded)
+	return true
+}
+
+// readSources produces the raw json of every entry the way marshalStruct writes it
+func (m *Mapper) readSources(plan *convertPlan, sourceValue reflect.Value) ([]entryValue, bool) {
+	values := make([]entryValue, len(plan.Entries))
+	for i := range plan.Sources {
+		source := &plan.Sources[i]
+		if !source.Needed {
+			continue
+		}
+		fieldValue, err := sourceValue.FieldByIndexErr(source.Info.Field.Index)
+		if err != nil || source.OmitEmpty && isEmptyReflectValue(fieldValue) {
+			// left out by encoding/json, behind a nil embedded pointer or empty
+			continue
+		}
+		raw, ok := encodeScalar(fieldValue)
+		if !ok {
+			return nil, false
+		}
+		if !source.Mapped {
+			values[source.JSONEntry] = entryValue{Raw: raw, Present: true}
+			continue
+		}
+		value, err := m.getValue(gjson.Parse(raw), source.Info, marshalling)
+		if err != nil || !gjson.Valid(value) {
+			return nil, false
+		}
+		if source.Info.OmitEmpty && isEmptyValue(value) {
+			continue
+		}
+		values[source.MappedEntry] = entryValue{Raw: strings.TrimSpace(value), Present: true}
+		if !(source.Info.Move || m.moveFields) || source.Info.Copy {
+			values[source.JSONEntry] = entryValue{Raw: raw, Present: true}
+		}
+	}
+	return values, true
+}
+
+// decodeTargets works out the value of every target the way unmarshalStruct does, and decodes it for its field
+func (m *Mapper) decodeTargets(plan *convertPlan, values []entryValue) ([]decodedScalar, bool) {
+	decoded := make([]decodedScalar, 0, len(plan.Targets))
+	for i := range plan.Targets {
+		target := &plan.Targets[i]
+		value, ok := m.mappedValue(target, values)
+		if ok && !value.Present {
+			value, ok = readValue(values, target.Key)
+		}
+		if !ok {
+			return nil, false
+		}
+		if !value.Present {
+			continue
+		}
+		scalar, ok := decodeScalar(value.Raw, target.Info.Field.Type)
+		if !ok {
+			return nil, false
+		}
+		scalar.Target = target
+		decoded = append(decoded, scalar)
+	}
+	return decoded, true
+}
+
+// mappedValue reads a mapped target from its paths, applying defaults, required and omitempty
+func (m *Mapper) mappedValue(target *convertTarget, values []entryValue) (entryValue, bool) {
+	if !target.Mapped {
+		return entryValue{}, true
+	}
+	info := target.Info
+	found, ok := lookupEntries(values, target.Reads)
+	if !ok {
+		return entryValue{}, false
+	}
+	missing := !found.Present || found.Raw == "null"
+	if info.Required && missing {
+		return entryValue{}, false
+	}
+	var value string
+	var err error
+	if info.Default != nil && missing {
+		value, err = m.getCoercedValue(*info.Default, info, unmarshalling)
+	} else if !found.Present {
+		return entryValue{}, true
+	} else {
+		value, err = m.getValue(gjson.Parse(found.Raw), info, unmarshalling)
+	}
+	if err != nil || !gjson.Valid(value) {
+		return entryValue{}, false
+	}
+	if info.OmitEmpty && isEmptyValue(value) {
+		return entryValue{}, true
+	}
+	return entryValue{Raw: strings.TrimSpace(value), Present: true}, true
+}
+
+// lookupEntries mirrors tagInfo.lookup, the first path that isn't missing or null wins
+func lookupEntries(values []entryValue, reads []pathRead) (entryValue, bool) {
+	found, ok := readValue(values, reads[0])
+	if !ok || len(reads) == 1 || found.Present && found.Raw != "null" {
+		return found, ok
+	}
+	for _, read := range reads[1:] {
+		fallback, ok := readValue(values, read)
+		if !ok {
+			return entryValue{}, false
+		}
+		if fallback.Present && fallback.Raw != "null" {
+			return fallback, true
+		}
+		if !found.Present && fallback.Present {
+			found = fallback
+		}
+	}
+	return found, true
+}
+
+// readValue returns the value at read, and false when it would be an object built out of entries
+func readValue(values []entryValue, read pathRead) (entryValue, bool) {
+	for _, entry := range read.Entries {
+		if values[entry].Present {
+			return values[entry], true
+		}
+	}
+	for _, entry := range read.Objects {
+		if values[entry].Present {
+			return entryValue{}, false
+		}
+	}
+	return entryValue{}, true
+}
+
+// setTargets writes the decoded values into their fields the way encoding/json does
+func setTargets(structValue reflect.Value, decoded []decodedScalar) {
+	for _, scalar := range decoded {
+		field := structValue.FieldByIndex(scalar.Target.Info.Field.Index)
+		if field.Kind() == reflect.Ptr {
+			if scalar.Null {
+				field.Set(reflect.Zero(field.Type()))
+				continue
+			}
+			if field.IsNil() {
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+			field = field.Elem()
+		} else if scalar.Null {
+			// encoding/json leaves values alone when decoding null into them
+			continue
+		}
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(scalar.Str)
+		case reflect.Bool:
+			field.SetBool(scalar.Bool)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			field.SetInt(scalar.Int)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			field.SetUint(scalar.Uint)
+		case reflect.Float32, reflect.Float64:
+			field.SetFloat(scalar.Float)
+		}
+	}
+}
+
+// encodeScalar returns the json encoding/json produces for a plain value, and false for floats it can't encode
+func encodeScalar(value reflect.Value) (string, bool) {
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return "null", true
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return encodeString(value.String()), true
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(value.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return encodeFloat(value.Float(), value.Type().Bits())
+	}
+	return "", false
+}
+
+// encodeString quotes s, leaving anything that encoding/json would escape to encoding/json
+func encodeString(s string) string {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < ' ' || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' || c >= utf8.RuneSelf {
+			raw, _ := json.Marshal(s)
+			return string(raw)
+		}
+	}
+	return `"` + s + `"`
+}
+
+// encodeFloat formats f the way encoding/json does, with exponents only for very large and very small numbers
+func encodeFloat(f float64, bits int) (string, bool) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return "", false
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	raw := strconv.AppendFloat(nil, f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		if n := len(raw); n >= 4 && raw[n-4] == 'e' && raw[n-3] == '-' && raw[n-2] == '0' {
+			raw[n-2] = raw[n-1]
+			raw = raw[:n-1]
+		}
+	}
+	return string(raw), true
+}
+
+// decodeScalar decodes raw json for a field of typ, and returns false where encoding/json would fail
+func decodeScalar(raw string, typ reflect.Type) (decodedScalar, bool) {
+	if raw == "null" {
+		return decodedScalar{Null: true}, true
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	scalar := decodedScalar{}
+	var err error
+	isNumber := raw != "" && (raw[0] == '-' || raw[0] >= '0' && raw[0] <= '9')
+	switch typ.Kind() {
+	case reflect.String:
+		if raw == "" || raw[0] != '"' {
+			return scalar, false
+		}
+		if strings.IndexByte(raw, '\\') == -1 && utf8.ValidString(raw) {
+			scalar.Str = raw[1 : len(raw)-1]
+		} else {
+			err = json.Unmarshal([]byte(raw), &scalar.Str)
+		}
+	case reflect.Bool:
+		if raw != "true" && raw != "false" {
+			return scalar, false
+		}
+		scalar.Bool = raw == "true"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !isNumber {
+			return scalar, false
+		}
+		scalar.Int, err = strconv.ParseInt(raw, 10, typ.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if !isNumber {
+			return scalar, false
+		}
+		scalar.Uint, err = strconv.ParseUint(raw, 10, typ.Bits())
+	case reflect.Float32, reflect.Float64:
+		if !isNumber {
+			return scalar, false
+		}
+		scalar.Float, err = strconv.ParseFloat(raw, typ.Bits())
+	default:
+		return scalar, false
+	}
+	return scalar, err == nil
+}
+
+// isPlainField reports whether field holds a string, bool or number, or a pointer to one, that encoding/json handles itself
+func isPlainField(field tagInfo) bool {
+	typ := field.Field.Type
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if hasCustomEncoding(typ) {
+		return false
+	}
+	switch typ.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// hasCustomEncoding reports whether values of typ encode or decode themselves, or have hooks that must run
+func hasCustomEncoding(typ reflect.Type) bool {
+	if getTypeHooks(typ).any() {
+		return true
+	}
+	for _, t := range []reflect.Type{typ, reflect.PointerTo(typ)} {
+		if t.Implements(jsonMarshalerType) || t.Implements(jsonUnmarshalerType) || t.Implements(textMarshalerType) || t.Implements(textUnmarshalerType) {
+			return true
+		}
+	}
+	return false
+}
+
+func isFloat(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Float32 || typ.Kind() == reflect.Float64
+}
+
+// plainJSONOptions returns whether the json tag of field has omitempty, and false if it has any other option
+func plainJSONOptions(field tagInfo) (bool, bool) {
+	_, options, _ := strings.Cut(field.Field.Tag.Get(jsonTagName), ",")
+	return options == omitEmpty, options == "" || options == omitEmpty
+}
+
+// embedsPointer reports whether the field at index is promoted through an embedded struct pointer
+func embedsPointer(typ reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		typ = typ.Field(i).Type
+		if typ.Kind() == reflect.Ptr {
+			return true
+		}
+	}
+	return false
+}
+
+// plainPath splits a path made of nothing but object keys into its keys, and returns false for any other path.
+// Keys that look like array indexes are rejected too, sjson writes arrays for them.
+func plainPath(path string) ([]string, bool) {
+	components := splitOutside(path, '.')
+	for i, component := range components {
+		key := unescapePathKey(component)
+		if key == "" || escapePathKey(key) != component {
+			return nil, false
+		}
+		if _, err := strconv.Atoi(key); err == nil {
+			return nil, false
+		}
+		components[i] = key
+	}
+	return components, true
+}
+
+func equalPath(a, b []string) bool {
+	return len(a) == len(b) && hasPathPrefix(a, b)
+}
+
+func isStrictPrefix(prefix, path []string) bool {
+	return len(prefix) < len(path) && hasPathPrefix(path, prefix)
+}
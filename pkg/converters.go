@@ -0,0 +1,95 @@
+package pkg
+
+import (
+	"github.com/tidwall/gjson"
+	"reflect"
+	"sync"
+)
+
+// ConverterFunc builds the value of a coerced field from the json found at its path.
+// The returned value is json encoded and written in place of the original json, so on Unmarshal it has to
+// decode into the field's type.
+type ConverterFunc func(result gjson.Result) (any, error)
+
+type converterKey struct {
+	From gjson.Type
+	To   reflect.Type
+}
+
+type converterRegistry struct {
+	lock       sync.RWMutex
+	converters map[converterKey]ConverterFunc
+}
+
+// globalConverters are consulted by every Mapper after its own converters
+var globalConverters = newConverterRegistry()
+
+func newConverterRegistry() *converterRegistry {
+	return &converterRegistry{converters: map[converterKey]ConverterFunc{}}
+}
+
+func (r *converterRegistry) register(from gjson.Type, to reflect.Type, fn ConverterFunc) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.converters[converterKey{From: from, To: to}] = fn
+}
+
+func (r *converterRegistry) unregister(from gjson.Type, to reflect.Type) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.converters, converterKey{From: from, To: to})
+}
+
+// convertsTo reports whether a converter is registered for any of types
+func (r *converterRegistry) convertsTo(types []reflect.Type) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for key := range r.converters {
+		for _, typ := range types {
+			if key.To == typ {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *converterRegistry) lookup(from gjson.Type, to reflect.Type) (ConverterFunc, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	fn, ok := r.converters[converterKey{From: from, To: to}]
+	return fn, ok
+}
+
+// RegisterConverter teaches coerce how to build a value of type to from json of type from, for every Mapper.
+// Converters run for coerced fields in both directions: on Unmarshal with the json found at the mapped path,
+// and on Marshal with the field's own json encoding.
+func RegisterConverter(from gjson.Type, to reflect.Type, fn ConverterFunc) {
+	globalConverters.register(from, to, fn)
+}
+
+// UnregisterConverter removes the global converter for the given types, e.g. once a test that registered it is done.
+// Mappers fall back to the default coercion, and generated code is used again once no global converters are left.
+func UnregisterConverter(from gjson.Type, to reflect.Type) {
+	globalConverters.unregister(from, to)
+}
+
+// RegisterConverter registers a converter that is only used by m, it takes precedence over a global converter for the same types
+func (m *Mapper) RegisterConverter(from gjson.Type, to reflect.Type, fn ConverterFunc) {
+	m.converters.register(from, to, fn)
+}
+
+// WithConverter registers a converter that is only used by the new Mapper
+func WithConverter(from gjson.Type, to reflect.Type, fn ConverterFunc) Option {
+	return func(m *Mapper) {
+		m.RegisterConverter(from, to, fn)
+	}
+}
+
+// getConverter finds the converter for the given json type and field type, preferring the ones registered on m
+func (m *Mapper) getConverter(from gjson.Type, to reflect.Type) (ConverterFunc, bool) {
+	if fn, ok := m.converters.lookup(from, to); ok {
+		return fn, true
+	}
+	return globalConverters.lookup(from, to)
+}
@@ -0,0 +1,134 @@
+package pkg
+
+import (
+	"encoding/json"
+	"github.com/joomcode/errorx"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// Decoder reads json values from a stream and maps them like Unmarshal. Slices are decoded one element at a time,
+// so only the element being mapped is held in memory, not the whole array.
+type Decoder struct {
+	mapper *Mapper
+	dec    *json.Decoder
+	ndjson bool
+	// raw holds the json of the value being mapped, its buffer is reused for every element
+	raw json.RawMessage
+}
+
+// NewDecoder returns a Decoder reading from r with the default Mapper
+func NewDecoder(r io.Reader) *Decoder {
+	return defaultMapper.NewDecoder(r)
+}
+
+// NewDecoder returns a Decoder reading from r that maps values with m
+func (m *Mapper) NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{mapper: m, dec: json.NewDecoder(r)}
+}
+
+// SetNDJSON makes slices decode from a stream of json values, one per line, instead of a json array
+func (d *Decoder) SetNDJSON(ndjson bool) {
+	d.ndjson = ndjson
+}
+
+// Decode reads the next value from the stream into v, which must be a pointer, applying mapper tags like Unmarshal.
+// A slice is filled from the elements of the next json array as they are read, or from every value left in the
+// stream when decoding NDJSON. Decode returns io.EOF when the stream has no values left.
+func (d *Decoder) Decode(v any) error {
+	vValue := reflect.ValueOf(v)
+	if vValue.Kind() != reflect.Ptr || vValue.IsNil() {
+		return errorx.IllegalArgument.New("Cannot Decode to nil or non pointer")
+	}
+	sliceValue := vValue.Elem()
+	// byte slices, and slices that decode themselves or have hooks, are mapped as a whole
+	if sliceValue.Kind() != reflect.Slice || !streamsType(sliceValue.Type()) {
+		if err := d.dec.Decode(&d.raw); err != nil {
+			return err
+		}
+		return d.mapper.Unmarshal(d.raw, v)
+	}
+
+	// appended to one by one, growing like append does
+	slice := reflect.New(sliceValue.Type()).Elem()
+	slice.Set(reflect.MakeSlice(sliceValue.Type(), 0, 0))
+	zero := reflect.Zero(sliceValue.Type().Elem())
+	null, err := d.each(func(data []byte) error {
+		slice.Set(reflect.Append(slice, zero))
+		return d.mapper.unmarshalValue(data, slice.Index(slice.Len()-1))
+	}, nil)
+	if null {
+		sliceValue.Set(reflect.Zero(sliceValue.Type()))
+	} else {
+		// like Unmarshal, the elements read before an error are kept
+		sliceValue.Set(slice)
+	}
+	return err
+}
+
+// DecodeEach reads the elements of the next json array in the stream, or every value left in it when decoding NDJSON,
+// and calls fn with each of them as soon as it is mapped. Decoding stops at the first error fn returns. Elements that
+// fail to map are skipped when the Mapper collects errors, which are returned once the stream is read.
+func DecodeEach[T any](d *Decoder, fn func(T) error) error {
+	var element T
+	elementValue := reflect.ValueOf(&element).Elem()
+	zero := reflect.Zero(elementValue.Type())
+	_, err := d.each(func(data []byte) error {
+		elementValue.Set(zero)
+		return d.mapper.unmarshalValue(data, elementValue)
+	}, func() error {
+		return fn(element)
+	})
+	return err
+}
+
+// each reads the elements of the next json array, or the values of an NDJSON stream, and maps each of them with decode.
+// use is called after every element that mapped without errors. It reports whether the next value was null instead
+// of an array.
+func (d *Decoder) each(decode func(data []byte) error, use func() error) (bool, error) {
+	errs := MappingErrors{}
+	if !d.ndjson {
+		token, err := d.dec.Token()
+		if err != nil {
+			return false, err
+		}
+		if token == nil {
+			return true, nil
+		}
+		if token != json.Delim('[') {
+			return false, errorx.IllegalArgument.New("cannot decode %v into a slice", token)
+		}
+	}
+	// More looks for the next element of the array, or the next value of the stream
+	for i := 0; d.dec.More(); i++ {
+		// json.Decoder reports bad syntax itself, the values it hands out are valid json like Unmarshal expects
+		if err := d.dec.Decode(&d.raw); err != nil {
+			return false, err
+		}
+		if err := decode(d.raw); err != nil {
+			err = nestedError(err, "["+strconv.Itoa(i)+"]", strconv.Itoa(i), i)
+			if d.mapper.unknownFields == WarnUnknownFields {
+				err = d.mapper.warnUnknownFields(err)
+			}
+			if err != nil {
+				if err = d.mapper.handleError(&errs, err); err != nil {
+					return false, err
+				}
+				continue
+			}
+		}
+		if use != nil {
+			if err := use(); err != nil {
+				return false, err
+			}
+		}
+	}
+	if !d.ndjson {
+		// the closing bracket
+		if _, err := d.dec.Token(); err != nil {
+			return false, err
+		}
+	}
+	return false, errs.err()
+}
@@ -0,0 +1,120 @@
+package pkg
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+)
+
+// encoderFlushSize is how much of a slice an Encoder buffers before writing it out
+const encoderFlushSize = 32 * 1024
+
+// Encoder writes mapped json values to a stream. Slices are written element by element through a buffer that
+// is reused for every value, so they never have to be held in memory as a whole.
+type Encoder struct {
+	mapper *Mapper
+	w      io.Writer
+	ndjson bool
+	buf    bytes.Buffer
+}
+
+// NewEncoder returns an Encoder writing to w with the default Mapper
+func NewEncoder(w io.Writer) *Encoder {
+	return defaultMapper.NewEncoder(w)
+}
+
+// NewEncoder returns an Encoder writing to w that maps values with m
+func (m *Mapper) NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{mapper: m, w: w}
+}
+
+// SetNDJSON makes slices encode to one json value per line instead of a json array
+func (e *Encoder) SetNDJSON(ndjson bool) {
+	e.ndjson = ndjson
+}
+
+// Encode writes the json encoding of v, with every mapper tagged field written to its mapped path like Marshal does,
+// followed by a newline. Slices and arrays are written as their elements are marshaled, as a json array or one
+// element per line when encoding NDJSON. When an element fails and errors aren't collected, the elements
+// before it may already be written.
+func (e *Encoder) Encode(v any) error {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() == reflect.Slice && value.IsNil() && e.ndjson {
+		// no elements, no lines
+		return nil
+	}
+	if !streamsElements(value) {
+		data, err := e.mapper.Marshal(v)
+		if err != nil {
+			return err
+		}
+		e.buf.Write(data)
+		e.buf.WriteByte('\n')
+		return e.flush()
+	}
+
+	errs := MappingErrors{}
+	if !e.ndjson {
+		e.buf.WriteByte('[')
+	}
+	err := e.mapper.marshalElements(value, &errs, func(i int, elemBytes []byte) error {
+		if e.ndjson {
+			e.buf.Write(elemBytes)
+			e.buf.WriteByte('\n')
+		} else {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			e.buf.Write(elemBytes)
+		}
+		if e.buf.Len() < encoderFlushSize {
+			return nil
+		}
+		return e.flush()
+	})
+	if err != nil {
+		e.buf.Reset()
+		return err
+	}
+	if !e.ndjson {
+		e.buf.WriteString("]\n")
+	}
+	if err = e.flush(); err != nil {
+		return err
+	}
+	return errs.err()
+}
+
+// flush writes out the buffer and empties it for reuse
+func (e *Encoder) flush() error {
+	_, err := e.w.Write(e.buf.Bytes())
+	e.buf.Reset()
+	return err
+}
+
+// streamsElements reports whether value is a slice or array Encode writes element by element. Nil slices are
+// marshaled whole, like the types streamsType leaves out.
+func streamsElements(value reflect.Value) bool {
+	if !value.IsValid() || value.Kind() == reflect.Slice && value.IsNil() {
+		return false
+	}
+	return streamsType(value.Type())
+}
+
+// streamsType reports whether values of typ can be encoded and decoded element by element. Values that encode
+// themselves or have hooks, and byte slices, which encoding/json writes as base64, are mapped whole.
+func streamsType(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return false
+		}
+	case reflect.Array:
+	default:
+		return false
+	}
+	return !hasJSONEncoding(typ) && !getTypeHooks(typ).any()
+}
@@ -0,0 +1,205 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/joomcode/errorx"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Errors is the errorx namespace of the error types specific to mapper
+	Errors = errorx.NewNamespace("mapper")
+	// CoercionFailed is returned by strict coercion when a value can't be converted without losing data
+	CoercionFailed = Errors.NewType("coercion_failed")
+	// RequiredFieldMissing is returned when the mapped path of a required field is missing or null
+	RequiredFieldMissing = Errors.NewType("required_field_missing")
+	// UnknownField is returned for keys that no field reads, when the Mapper rejects unknown fields
+	UnknownField = Errors.NewType("unknown_field")
+	// InvalidPath is returned for mapper paths that don't follow the path grammar, or can't be used in the direction they're mapped in
+	InvalidPath = Errors.NewType("invalid_path")
+	// TransformFailed is returned when a transform can't be applied to a value, or isn't registered
+	TransformFailed = Errors.NewType("transform_failed")
+)
+
+// MappingError is returned when a single value can't be mapped. It wraps the original error,
+// so errors.As, errors.Is and errorx type checks still see the cause.
+type MappingError struct {
+	// Field is the Go field the value belongs to, e.g. Customers[2].Address.Zip
+	Field string
+	// MapperPath is the path from the field's mapper tag, relative to the object the field belongs to
+	MapperPath string
+	// JSONPath is the full path of the value in the json document being read or written
+	JSONPath string
+	// Index is the position of the element in the innermost slice the error came from, or -1
+	Index int
+	Err   error
+}
+
+func (e *MappingError) Error() string {
+	location := []string{}
+	if e.Field != "" {
+		location = append(location, "field "+e.Field)
+	}
+	if e.JSONPath != "" {
+		location = append(location, "path "+e.JSONPath)
+	}
+	if e.Index >= 0 {
+		location = append(location, "index "+strconv.Itoa(e.Index))
+	}
+	if len(location) == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %s", strings.Join(location, ", "), e.Err.Error())
+}
+
+func (e *MappingError) Unwrap() error {
+	return e.Err
+}
+
+// MappingErrors holds every MappingError found when a Mapper aggregates errors
+type MappingErrors []*MappingError
+
+func (e MappingErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("%d mapping errors: %s", len(e), strings.Join(messages, "; "))
+}
+
+func (e MappingErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// add appends err, flattening other MappingErrors
+func (e *MappingErrors) add(err error) {
+	var many MappingErrors
+	if errors.As(err, &many) {
+		*e = append(*e, many...)
+		return
+	}
+	*e = append(*e, asMappingError(err))
+}
+
+// err returns nil when nothing was collected, so a nil MappingErrors never ends up in a non-nil error
+func (e MappingErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// WithErrorAggregation makes the Mapper carry on after a value fails to map and return every failure as MappingErrors
+func WithErrorAggregation() Option {
+	return func(m *Mapper) {
+		m.aggregateErrors = true
+	}
+}
+
+// handleError returns err when failing fast. When aggregating errors it's added to errs and nil is returned so mapping carries on.
+// Missing required fields and unknown fields are always collected, so a single error can name all of them.
+func (m *Mapper) handleError(errs *MappingErrors, err error) error {
+	if !m.aggregateErrors && !alwaysCollected(err) {
+		return err
+	}
+	errs.add(err)
+	return nil
+}
+
+func alwaysCollected(err error) bool {
+	var many MappingErrors
+	if !errors.As(err, &many) {
+		return errorx.IsOfType(err, RequiredFieldMissing) || errorx.IsOfType(err, UnknownField)
+	}
+	for _, mappingErr := range many {
+		if !errorx.IsOfType(mappingErr, RequiredFieldMissing) && !errorx.IsOfType(mappingErr, UnknownField) {
+			return false
+		}
+	}
+	return true
+}
+
+func asMappingError(err error) *MappingError {
+	var mappingErr *MappingError
+	if errors.As(err, &mappingErr) {
+		return mappingErr
+	}
+	return &MappingError{Index: -1, Err: err}
+}
+
+// fieldError annotates err with the field it came from and the path of the value that failed
+func fieldError(err error, tagData tagInfo, jsonPath string, dir direction) error {
+	return &MappingError{
+		Field:      tagData.Field.Name,
+		MapperPath: tagData.mapperPath(dir),
+		JSONPath:   jsonPath,
+		Index:      -1,
+		Err:        err,
+	}
+}
+
+// nestedError prefixes errors that came from inside a nested value with where that value sits in its parent
+func nestedError(err error, field, jsonPath string, index int) error {
+	var many MappingErrors
+	if errors.As(err, &many) {
+		prefixed := make(MappingErrors, len(many))
+		for i, mappingErr := range many {
+			prefixed[i] = prefixMappingError(mappingErr, field, jsonPath, index)
+		}
+		return prefixed
+	}
+	return prefixMappingError(asMappingError(err), field, jsonPath, index)
+}
+
+func prefixMappingError(err *MappingError, field, jsonPath string, index int) *MappingError {
+	prefixed := *err
+	switch {
+	case prefixed.Field == "":
+		prefixed.Field = field
+	case strings.HasPrefix(prefixed.Field, "["):
+		prefixed.Field = field + prefixed.Field
+	case field != "":
+		prefixed.Field = field + "." + prefixed.Field
+	}
+	switch {
+	case prefixed.JSONPath == "":
+		prefixed.JSONPath = jsonPath
+	case jsonPath != "":
+		prefixed.JSONPath = jsonPath + "." + prefixed.JSONPath
+	}
+	if prefixed.Index < 0 {
+		prefixed.Index = index
+	}
+	return &prefixed
+}
+
+// unmarshalError annotates an error from encoding/json with the mapped field it was decoding, if there is one
+func unmarshalError(err error, plan *typePlan) error {
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Field == "" {
+		return err
+	}
+	jsonName, rest, _ := strings.Cut(typeErr.Field, ".")
+	for _, field := range plan.Visible {
+		if field.JsonFieldName != jsonName {
+			continue
+		}
+		jsonPath := field.outputPath(unmarshalling)
+		if rest != "" {
+			jsonPath += "." + rest
+		}
+		mapperPath := ""
+		if field.MapperFieldPath != "" {
+			mapperPath = field.mapperPath(unmarshalling)
+		}
+		return &MappingError{Field: field.Field.Name, MapperPath: mapperPath, JSONPath: jsonPath, Index: -1, Err: err}
+	}
+	return &MappingError{JSONPath: typeErr.Field, Index: -1, Err: err}
+}
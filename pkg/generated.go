@@ -0,0 +1,65 @@
+package pkg
+
+import (
+	"reflect"
+)
+
+// MappedMarshaler is implemented by types with mapping code generated by cmd/mappergen. Marshal calls
+// MarshalMapped instead of applying the type's mapper tags with reflection.
+type MappedMarshaler interface {
+	MarshalMapped(m *Mapper) ([]byte, error)
+}
+
+// MappedUnmarshaler is implemented by types with mapping code generated by cmd/mappergen. Unmarshal calls
+// UnmarshalMapped instead of applying the type's mapper tags with reflection.
+type MappedUnmarshaler interface {
+	UnmarshalMapped(m *Mapper, data []byte) error
+}
+
+// GeneratedFieldError annotates an error from generated code with the field it came from, the way Marshal and Unmarshal do.
+// It is only meant to be called by generated code.
+func GeneratedFieldError(err error, field, mapperPath, jsonPath string) error {
+	return &MappingError{Field: field, MapperPath: mapperPath, JSONPath: jsonPath, Index: -1, Err: err}
+}
+
+// GeneratedNestedError prefixes an error from a nested value with the field it sits in. It is only meant to be called by generated code.
+func GeneratedNestedError(err error, field, jsonPath string) error {
+	return nestedError(err, field, jsonPath, -1)
+}
+
+// GeneratedUnmarshalError annotates an error from encoding/json with the field of v it was decoding. It is only meant
+// to be called by generated code.
+func GeneratedUnmarshalError(err error, v any) error {
+	return unmarshalError(err, getTypePlan(reflect.TypeOf(v).Elem(), tagNames{Mapper: mapperTagName, JSON: jsonTagName}))
+}
+
+// runsGenerated reports whether m maps values of typ exactly like generated code does. Generated code knows the tags
+// it was generated from and nothing else, so Mappers with other tags, options that change how values are mapped, or
+// converters for the types of typ's coerced fields, keep using reflection.
+func (m *Mapper) runsGenerated(typ reflect.Type) bool {
+	if m.tags != (tagNames{Mapper: mapperTagName, JSON: jsonTagName}) ||
+		m.aggregateErrors || m.strictCoercion || m.defaultCoercion || m.foldCase || m.moveFields ||
+		m.unknownFields != IgnoreUnknownFields {
+		return false
+	}
+	coerced := getTypePlan(typ, m.tags).Coerced
+	return len(coerced) == 0 || !m.converters.convertsTo(coerced) && !globalConverters.convertsTo(coerced)
+}
+
+// generatedMarshaler returns the generated marshaler of the struct in value, if it has one m can use
+func (m *Mapper) generatedMarshaler(value reflect.Value) (MappedMarshaler, bool) {
+	if !value.CanInterface() || !m.runsGenerated(value.Type()) {
+		return nil, false
+	}
+	marshaler, ok := value.Interface().(MappedMarshaler)
+	return marshaler, ok
+}
+
+// generatedUnmarshaler returns the generated unmarshaler of the struct in value, if it has one m can use
+func (m *Mapper) generatedUnmarshaler(value reflect.Value) (MappedUnmarshaler, bool) {
+	if !value.CanAddr() || !value.Addr().CanInterface() || !m.runsGenerated(value.Type()) {
+		return nil, false
+	}
+	unmarshaler, ok := value.Addr().Interface().(MappedUnmarshaler)
+	return unmarshaler, ok
+}
@@ -0,0 +1,81 @@
+package pkg
+
+import (
+	"reflect"
+	"sync"
+)
+
+// MapperMarshaler is implemented by types that map themselves. Marshal calls MarshalMapper instead of applying the
+// type's mapper tags, with the Mapper it runs with so nested values can be mapped the same way.
+type MapperMarshaler interface {
+	MarshalMapper(m *Mapper) ([]byte, error)
+}
+
+// MapperUnmarshaler is implemented by types that map themselves. Unmarshal calls UnmarshalMapper with the data of
+// the value instead of applying the type's mapper tags.
+type MapperUnmarshaler interface {
+	UnmarshalMapper(m *Mapper, data []byte) error
+}
+
+// BeforeMapper is implemented by types that prepare themselves to be marshaled, e.g. to compute derived fields.
+// Marshal calls BeforeMap on a copy of the value before it is mapped, so the fields of the value passed in are left as they were.
+type BeforeMapper interface {
+	BeforeMap() error
+}
+
+// AfterMapper is implemented by types that finish themselves after being unmarshaled, e.g. to normalize their fields.
+// Unmarshal calls AfterMap once the value and everything inside it is mapped.
+type AfterMapper interface {
+	AfterMap() error
+}
+
+// typeHooks tells which of the hook interfaces a type implements, with pointer receivers included
+type typeHooks struct {
+	Marshaler   bool
+	Unmarshaler bool
+	Before      bool
+	After       bool
+}
+
+var (
+	// hooksCache holds the typeHooks of every reflect.Type checked so far
+	hooksCache sync.Map
+
+	mapperMarshalerType   = reflect.TypeOf((*MapperMarshaler)(nil)).Elem()
+	mapperUnmarshalerType = reflect.TypeOf((*MapperUnmarshaler)(nil)).Elem()
+	beforeMapperType      = reflect.TypeOf((*BeforeMapper)(nil)).Elem()
+	afterMapperType       = reflect.TypeOf((*AfterMapper)(nil)).Elem()
+)
+
+// getTypeHooks returns the cached hooks of typ
+func getTypeHooks(typ reflect.Type) typeHooks {
+	if cached, ok := hooksCache.Load(typ); ok {
+		return cached.(typeHooks)
+	}
+	pointer := reflect.PointerTo(typ)
+	hooks := typeHooks{
+		Marshaler:   pointer.Implements(mapperMarshalerType),
+		Unmarshaler: pointer.Implements(mapperUnmarshalerType),
+		Before:      pointer.Implements(beforeMapperType),
+		After:       pointer.Implements(afterMapperType),
+	}
+	hooksCache.Store(typ, hooks)
+	return hooks
+}
+
+func (h typeHooks) any() bool {
+	return h.Marshaler || h.Unmarshaler || h.Before || h.After
+}
+
+// hasJSONEncoding reports whether encoding/json leaves the encoding of typ to the type itself
+func hasJSONEncoding(typ reflect.Type) bool {
+	return typ.Implements(jsonMarshalerType) || reflect.PointerTo(typ).Implements(jsonUnmarshalerType)
+}
+
+// addressableCopy returns an addressable copy of value, so hooks with pointer receivers can be called without
+// changing the caller's data
+func addressableCopy(value reflect.Value) reflect.Value {
+	copied := reflect.New(value.Type()).Elem()
+	copied.Set(value)
+	return copied
+}
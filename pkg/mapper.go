@@ -1,12 +1,17 @@
 package pkg
 
 import (
+	"bytes"
+	"encoding"
 	"encoding/json"
 	"github.com/joomcode/errorx"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -15,6 +20,16 @@ const (
 	omitEmpty     = "omitempty"
 	asString      = "string"
 	coerce        = "coerce"
+	layoutPrefix  = "layout="
+	tzPrefix      = "tz="
+	defaultPrefix = "default="
+	required      = "required"
+	inPrefix      = "in="
+	outPrefix     = "out="
+	readOnly      = "readonly"
+	writeOnly     = "writeonly"
+	move          = "move"
+	copyOption    = "copy"
 )
 
 type tagInfo struct {
@@ -24,210 +39,880 @@ type tagInfo struct {
 	JsonFieldName   string
 	OmitEmpty       bool
 	Coerce          bool
+	// DocumentKey is the field's key in mapped documents, its json key unless the Mapper reads keys from another tag
+	DocumentKey string
+	// FallbackPaths are read in order when MapperFieldPath is missing or null
+	FallbackPaths []string
+	// OutPath is written on marshal, it is MapperFieldPath unless the tag has out=
+	OutPath string
+	// Transforms run on the value read on unmarshal, their inverses on the value written on marshal
+	Transforms []transformStep
+	// ReadPaths are the gjson paths MapperFieldPath and FallbackPaths are read from
+	ReadPaths []string
+	// Projected is set when one of the field's paths is a projection like items.#.id, its elements are coerced one by one
+	Projected bool
+	// OutProjection splits OutPath when it is a projection
+	OutProjection *projection
+	// PathError is set when one of the read paths is invalid, OutPathError when OutPath is invalid or can't be written
+	PathError    error
+	OutPathError error
+	// ReadOnly fields are only mapped on unmarshal, WriteOnly fields only on marshal
+	ReadOnly  bool
+	WriteOnly bool
+	// Remain fields collect the keys no other field reads
+	Remain bool
+	// Move drops the field's json key from marshaled output once it is written to OutPath, Copy keeps it even when the Mapper moves fields
+	Move bool
+	Copy bool
+	// Strict coercion fails instead of losing data
+	Strict bool
+	// Default is used when the mapped path is missing or null
+	Default *gjson.Result
+	// Required fields fail to unmarshal when the mapped path is missing or null
+	Required bool
+	// Layout is the time layout, or epoch unit, used when coercing times
+	Layout string
+	// TimeZone names the location coerced times are converted to, Location is nil if it doesn't exist
+	TimeZone string
+	Location *time.Location
 }
 
+// direction tells value conversions which way the data is flowing
+type direction int
+
+const (
+	// unmarshalling reads external data at the mapped path into a field
+	unmarshalling direction = iota
+	// marshalling writes a field's own json encoding to the mapped path
+	marshalling
+)
+
 type change struct {
-	Path  string
-	Value []byte
+	Path    string
+	Value   []byte
+	TagData tagInfo
+}
+
+// Mapper marshals and unmarshals values while applying mapper tags. Create one with New,
+// the package level functions use a default Mapper.
+type Mapper struct {
+	// tags are the struct tags mapped paths and document keys are read from
+	tags            tagNames
+	converters      *converterRegistry
+	transforms      *transformRegistry
+	aggregateErrors bool
+	strictCoercion  bool
+	defaultCoercion bool
+	foldCase        bool
+	moveFields      bool
+	unknownFields   UnknownFieldPolicy
+	warnUnknown     func(path string)
+}
+
+// Option configures a Mapper
+type Option func(m *Mapper)
+
+var defaultMapper = New()
+
+// New creates a Mapper configured with opts
+func New(opts ...Option) *Mapper {
+	m := &Mapper{
+		tags:       tagNames{Mapper: mapperTagName, JSON: jsonTagName},
+		converters: newConverterRegistry(),
+		transforms: newTransformRegistry(map[string]transform{}),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// WithTagName reads mapped paths from the named struct tag instead of the mapper tag. Profiles use tags
+// named after it, so `WithTagName("map")` makes the stripe profile read `map_stripe` tags.
+func WithTagName(name string) Option {
+	return func(m *Mapper) {
+		m.tags.Mapper = name
+	}
+}
+
+// WithJSONTagName reads the keys fields have in mapped documents from the named struct tag. Fields without
+// the tag keep their json key, and which fields are encoded is still decided by encoding/json.
+func WithJSONTagName(name string) Option {
+	return func(m *Mapper) {
+		m.tags.JSON = name
+	}
+}
+
+// WithDefaultCoercion coerces every mapped field, as if all mapper tags had the coerce option
+func WithDefaultCoercion() Option {
+	return func(m *Mapper) {
+		m.defaultCoercion = true
+	}
+}
+
+// WithMovedFields makes Marshal move mapped fields to their mapped paths instead of copying them, so the output
+// only has the mapped shape. Fields tagged with copy still keep their json key.
+func WithMovedFields() Option {
+	return func(m *Mapper) {
+		m.moveFields = true
+	}
+}
+
+// WithCaseInsensitivePaths matches the object keys in mapped paths ignoring case when reading them,
+// an exact match is still preferred
+func WithCaseInsensitivePaths() Option {
+	return func(m *Mapper) {
+		m.foldCase = true
+	}
 }
 
 func Convert(source, dest interface{}) error {
-	sourceBytes, err := Marshal(source)
+	return defaultMapper.Convert(source, dest)
+}
+
+func Marshal(v any) ([]byte, error) {
+	return defaultMapper.Marshal(v)
+}
+
+func Unmarshal(data []byte, v interface{}) error {
+	return defaultMapper.Unmarshal(data, v)
+}
+
+// Convert marshals source and unmarshals the result into dest, which must be a pointer. Structs of plain values
+// are copied field by field instead, with the same result as the json round trip.
+func (m *Mapper) Convert(source, dest interface{}) error {
+	if m.convertDirect(source, dest) {
+		return nil
+	}
+	sourceBytes, err := m.Marshal(source)
 	if err != nil {
 		return err
 	}
-	return Unmarshal(sourceBytes, dest)
+	return m.Unmarshal(sourceBytes, dest)
 }
 
-func Marshal(v any) ([]byte, error) {
-	if isSlice(v) {
-		return marshalSlice(v)
+// Marshal returns the json encoding of v with every mapper tagged field written to its mapped path
+func (m *Mapper) Marshal(v any) ([]byte, error) {
+	if v == nil {
+		return []byte("null"), nil
 	}
-	if isStruct(v) {
-		return marshalStruct(v)
+	return m.marshalValue(reflect.ValueOf(v))
+}
+
+// marshalValue marshals any value, applying mapper tags to every struct found along the way
+func (m *Mapper) marshalValue(value reflect.Value) ([]byte, error) {
+	if !typeNeedsMapping(value.Type(), m.tags) {
+		return json.Marshal(value.Interface())
 	}
-	return nil, errorx.IllegalArgument.New("unsupported type")
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return []byte("null"), nil
+		}
+		return m.marshalValue(value.Elem())
+	}
+	if value.Kind() == reflect.Interface {
+		// mapped by the type of the value inside
+		if value.IsNil() {
+			return []byte("null"), nil
+		}
+		return m.marshalValue(value.Elem())
+	}
+	if hooks := getTypeHooks(value.Type()); hooks.any() && value.CanInterface() {
+		// hooks run on a copy, BeforeMap must not change what the caller passed in
+		value = addressableCopy(value)
+		if hooks.Before {
+			if err := value.Addr().Interface().(BeforeMapper).BeforeMap(); err != nil {
+				return nil, err
+			}
+		}
+		if hooks.Marshaler {
+			return value.Addr().Interface().(MapperMarshaler).MarshalMapper(m)
+		}
+	}
+	if hasJSONEncoding(value.Type()) {
+		// only the hooks are mapped, encoding/json does the rest
+		return json.Marshal(value.Interface())
+	}
+	if !m.holdsMapping(value) {
+		// nothing inside the interfaces needs mapping, encoding/json writes the whole of it
+		return json.Marshal(value.Interface())
+	}
+	switch value.Kind() {
+	case reflect.Struct:
+		return m.marshalStruct(value)
+	case reflect.Slice:
+		if value.IsNil() {
+			return []byte("null"), nil
+		}
+		return m.marshalSlice(value)
+	case reflect.Array:
+		return m.marshalSlice(value)
+	case reflect.Map:
+		if value.IsNil() {
+			return []byte("null"), nil
+		}
+		return m.marshalMap(value)
+	}
+	return json.Marshal(value.Interface())
 }
 
-func marshalSlice(v any) ([]byte, error) {
-	marshalledString := "["
-	sliceValue := reflect.ValueOf(v)
-	if sliceValue.Kind() == reflect.Ptr {
-		sliceValue = sliceValue.Elem()
+// holdsMapping reports whether a slice, array or map of interfaces holds a value whose type needs mapping. Other
+// values are decided by their type alone.
+func (m *Mapper) holdsMapping(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if value.Type().Elem().Kind() != reflect.Interface {
+			return true
+		}
+	default:
+		return true
 	}
+	if value.Kind() == reflect.Map {
+		iter := value.MapRange()
+		for iter.Next() {
+			if elem := iter.Value(); !elem.IsNil() && typeNeedsMapping(elem.Elem().Type(), m.tags) {
+				return true
+			}
+		}
+		return false
+	}
+	for i := 0; i < value.Len(); i++ {
+		if elem := value.Index(i); !elem.IsNil() && typeNeedsMapping(elem.Elem().Type(), m.tags) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Mapper) marshalSlice(sliceValue reflect.Value) ([]byte, error) {
+	errs := MappingErrors{}
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	err := m.marshalElements(sliceValue, &errs, func(i int, elemBytes []byte) error {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(elemBytes)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), errs.err()
+}
+
+// marshalElements marshals the elements of sliceValue in order and hands each of them to write. Elements that fail
+// are written as null when errors are collected in errs.
+func (m *Mapper) marshalElements(sliceValue reflect.Value, errs *MappingErrors, write func(i int, elemBytes []byte) error) error {
 	for i := 0; i < sliceValue.Len(); i++ {
-		structBytes, err := marshalStruct(sliceValue.Index(i).Interface())
+		elemBytes, err := m.marshalValue(sliceValue.Index(i))
+		if err != nil {
+			if err = m.handleError(errs, nestedError(err, "["+strconv.Itoa(i)+"]", strconv.Itoa(i), i)); err != nil {
+				return err
+			}
+			elemBytes = []byte("null")
+		}
+		if err = write(i, elemBytes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Mapper) marshalMap(mapValue reflect.Value) ([]byte, error) {
+	type entry struct {
+		key   string
+		value reflect.Value
+	}
+	entries := make([]entry, 0, mapValue.Len())
+	iter := mapValue.MapRange()
+	for iter.Next() {
+		key, err := mapKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{key: key, value: iter.Value()})
+	}
+	// encoding/json writes the keys in sorted order
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+	errs := MappingErrors{}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, entry := range entries {
+		elemBytes, err := m.marshalValue(entry.value)
+		if err != nil {
+			if err = m.handleError(&errs, nestedError(err, "["+strconv.Quote(entry.key)+"]", escapePathKey(entry.key), -1)); err != nil {
+				return nil, err
+			}
+			elemBytes = []byte("null")
+		}
+		keyBytes, err := json.Marshal(entry.key)
 		if err != nil {
 			return nil, err
 		}
-		marshalledString += string(structBytes)
-		if i+1 < sliceValue.Len() {
-			marshalledString += ","
+		if i > 0 {
+			buf.WriteByte(',')
 		}
+		buf.Write(keyBytes)
+		buf.WriteByte(':')
+		buf.Write(elemBytes)
 	}
-	marshalledString += "]"
-	return []byte(marshalledString), nil
+	buf.WriteByte('}')
+	return buf.Bytes(), errs.err()
 }
 
-func marshalStruct(v any) ([]byte, error) {
-	// read tags
-	tagDatas, err := getTagDatas(v)
-	if err != nil {
-		return nil, err
+// mapKey returns the object key encoding/json writes for a map key: strings as they are, then text marshalers,
+// then integers
+func mapKey(key reflect.Value) (string, error) {
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	}
+	if marshaler, ok := key.Interface().(encoding.TextMarshaler); ok {
+		if key.Kind() == reflect.Ptr && key.IsNil() {
+			return "", nil
+		}
+		text, err := marshaler.MarshalText()
+		if err != nil {
+			return "", &json.MarshalerError{Type: key.Type(), Err: err}
+		}
+		return string(text), nil
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
 	}
+	return "", &json.UnsupportedTypeError{Type: key.Type()}
+}
+
+func (m *Mapper) marshalStruct(structValue reflect.Value) ([]byte, error) {
+	if structValue.Kind() == reflect.Ptr {
+		structValue = structValue.Elem()
+	}
+	if generated, ok := m.generatedMarshaler(structValue); ok {
+		return generated.MarshalMapped(m)
+	}
+	// read tags
+	plan := getTypePlan(structValue.Type(), m.tags)
 	//marshall to json first
-	jsonBytes, err := json.Marshal(v)
+	jsonBytes, err := json.Marshal(structValue.Interface())
 	if err != nil {
 		return nil, err
 	}
 
+	errs := MappingErrors{}
+	// nested values are mapped first so the paths of this struct see their mapped shape
+	for _, nested := range plan.Nested {
+		jsonPath := escapePathKey(nested.JsonFieldName)
+		if !gjson.GetBytes(jsonBytes, jsonPath).Exists() {
+			// omitted by encoding/json
+			continue
+		}
+		nestedBytes, err := m.marshalValue(structValue.FieldByIndex(nested.Field.Index))
+		if err == nil {
+			jsonBytes, err = sjson.SetRawBytes(jsonBytes, jsonPath, nestedBytes)
+		}
+		if err != nil {
+			if err = m.handleError(&errs, nestedError(err, nested.Field.Name, nested.outputPath(marshalling), -1)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	changes := []change{}
 	// process any fields that have the mapper tag, track updates in case there is collision on tags
-	if len(tagDatas) > 0 {
-		for _, tagData := range tagDatas {
+	if len(plan.Fields) > 0 {
+		for _, tagData := range plan.Fields {
+			if tagData.ReadOnly {
+				continue
+			}
+			if tagData.OutPathError != nil {
+				if err := m.handleError(&errs, fieldError(tagData.OutPathError, tagData, tagData.OutPath, marshalling)); err != nil {
+					return nil, err
+				}
+				continue
+			}
 			// get the value from the json marshalled data
-			value, err := getValue(jsonBytes, tagData.JsonFieldName, tagData.Coerce, tagData.AsString, tagData.Field)
+			jsonPath := escapePathKey(tagData.JsonFieldName)
+			result := gjson.GetBytes(jsonBytes, jsonPath)
+			if !result.Exists() {
+				// left out by encoding/json, e.g. behind a nil embedded pointer
+				continue
+			}
+			value, err := m.getValue(result, tagData, marshalling)
 			if err != nil {
-				return nil, err
+				if err = m.handleError(&errs, fieldError(err, tagData, tagData.OutPath, marshalling)); err != nil {
+					return nil, err
+				}
+				continue
 			}
 			if tagData.OmitEmpty && isEmptyValue(value) {
-				jsonBytes, err = sjson.DeleteBytes(jsonBytes, tagData.JsonFieldName)
+				jsonBytes, err = sjson.DeleteBytes(jsonBytes, jsonPath)
 				if err != nil {
-					return nil, err
+					return nil, fieldError(err, tagData, jsonPath, marshalling)
 				}
 				continue
 			}
-			changes = append(changes, change{Path: tagData.MapperFieldPath, Value: []byte(value)})
+			if (tagData.Move || m.moveFields) && !tagData.Copy {
+				// only the mapped path is written, the field's own key is dropped
+				jsonBytes, err = sjson.DeleteBytes(jsonBytes, jsonPath)
+				if err != nil {
+					return nil, fieldError(err, tagData, jsonPath, marshalling)
+				}
+			}
+			changes = append(changes, change{Path: tagData.OutPath, Value: []byte(value), TagData: tagData})
 		}
+	}
+
+	// move the fields that are known by another name to their document keys, before mapped values are written there
+	for _, renamed := range plan.Renamed {
+		jsonBytes, err = moveKey(jsonBytes, renamed.JsonFieldName, renamed.DocumentKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(changes) > 0 {
 		// apply updates
 		for _, change := range changes {
 			// set the value at the mapped path
-			jsonBytes, err = sjson.SetRawBytes(jsonBytes, change.Path, change.Value)
+			jsonBytes, err = setPath(jsonBytes, change.Path, change.TagData.OutProjection, change.Value)
 			if err != nil {
+				if err = m.handleError(&errs, fieldError(err, change.TagData, change.Path, marshalling)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if plan.Remain != nil {
+		merged, err := mergeRemain(jsonBytes, plan)
+		if err == nil {
+			jsonBytes = merged
+		} else {
+			if err = m.handleError(&errs, fieldError(err, *plan.Remain, plan.Remain.OutPath, marshalling)); err != nil {
 				return nil, err
 			}
 		}
 	}
 
-	return jsonBytes, nil
+	return jsonBytes, errs.err()
 }
 
-func Unmarshal(data []byte, v interface{}) error {
+// Unmarshal parses data into v, which must be a pointer, reading every mapper tagged field from its mapped path
+func (m *Mapper) Unmarshal(data []byte, v interface{}) error {
 	vValue := reflect.ValueOf(v)
-	if vValue.IsNil() || vValue.Kind() != reflect.Ptr {
+	if vValue.Kind() != reflect.Ptr || vValue.IsNil() {
 		return errorx.IllegalArgument.New("Cannot Unmarshal to nil or non pointer")
 	}
-	if isSlice(v) {
-		return unmarshalSlice(data, v)
+	if !json.Valid(data) {
+		// nested values are read from pieces of data, so bad syntax anywhere is reported up front like encoding/json does
+		return json.Unmarshal(data, &json.RawMessage{})
 	}
-	if isStruct(v) {
-		return unmarshalStruct(data, v)
+	err := m.unmarshalValue(data, vValue.Elem())
+	if err != nil && m.unknownFields == WarnUnknownFields {
+		return m.warnUnknownFields(err)
 	}
-	return errorx.IllegalArgument.New("unsupported type")
+	return err
 }
 
-func unmarshalSlice(data []byte, v interface{}) (err error) {
-	sliceObjType := reflect.TypeOf(v).Elem().Elem()
-	gjson.GetBytes(data, "@this").ForEach(func(key, value gjson.Result) bool {
-		var newObj interface{}
-		if sliceObjType.Kind() == reflect.Ptr {
-			newObj = reflect.New(sliceObjType.Elem()).Interface()
-		} else {
-			newObj = reflect.New(sliceObjType).Interface()
+// unmarshalValue unmarshals data into value, which must be settable, applying mapper tags to every struct found along the way
+func (m *Mapper) unmarshalValue(data []byte, value reflect.Value) error {
+	if !typeNeedsMapping(value.Type(), m.tags) {
+		err := json.Unmarshal(data, value.Addr().Interface())
+		if err == nil && m.unknownFields != IgnoreUnknownFields {
+			err = m.unknownInType(gjson.ParseBytes(data), value.Type(), nil).err()
+		}
+		return err
+	}
+	if value.Kind() == reflect.Ptr {
+		if isNull(data) {
+			value.Set(reflect.Zero(value.Type()))
+			return nil
+		}
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		return m.unmarshalValue(data, value.Elem())
+	}
+	if value.Kind() == reflect.Interface {
+		// like encoding/json, a non-nil pointer inside the interface is decoded into, anything else is replaced
+		if !value.IsNil() && value.Elem().Kind() == reflect.Ptr && !value.Elem().IsNil() && !isNull(data) {
+			return m.unmarshalValue(data, value.Elem().Elem())
+		}
+		return json.Unmarshal(data, value.Addr().Interface())
+	}
+	hooks := getTypeHooks(value.Type())
+	var err error
+	switch {
+	case hooks.Unmarshaler:
+		err = value.Addr().Interface().(MapperUnmarshaler).UnmarshalMapper(m, data)
+	case hasJSONEncoding(value.Type()):
+		// only the hooks are mapped, encoding/json does the rest
+		err = json.Unmarshal(data, value.Addr().Interface())
+	default:
+		err = m.unmarshalKind(data, value)
+	}
+	if err == nil && hooks.After {
+		err = value.Addr().Interface().(AfterMapper).AfterMap()
+	}
+	return err
+}
+
+// unmarshalKind unmarshals data into value, which isn't a pointer, the way values of its kind are mapped
+func (m *Mapper) unmarshalKind(data []byte, value reflect.Value) error {
+	switch value.Kind() {
+	case reflect.Struct:
+		return m.unmarshalStruct(data, value)
+	case reflect.Slice, reflect.Array:
+		return m.unmarshalSlice(data, value)
+	case reflect.Map:
+		return m.unmarshalMap(data, value)
+	}
+	return json.Unmarshal(data, value.Addr().Interface())
+}
+
+func (m *Mapper) unmarshalSlice(data []byte, sliceValue reflect.Value) error {
+	result := gjson.ParseBytes(data)
+	if result.Type == gjson.Null {
+		// like encoding/json, null empties a slice and leaves an array as it is
+		if sliceValue.Kind() == reflect.Slice {
+			sliceValue.Set(reflect.Zero(sliceValue.Type()))
+		}
+		return nil
+	}
+	if !result.IsArray() {
+		return errorx.IllegalArgument.New("cannot unmarshal %s into %s", result.Type, sliceValue.Type())
+	}
+	elements := result.Array()
+	if sliceValue.Kind() == reflect.Slice {
+		sliceValue.Set(reflect.MakeSlice(sliceValue.Type(), len(elements), len(elements)))
+	}
+	errs := MappingErrors{}
+	for i, element := range elements {
+		if i >= sliceValue.Len() {
+			// encoding/json drops the extra elements of fixed size arrays
+			break
+		}
+		err := m.unmarshalValue([]byte(element.Raw), sliceValue.Index(i))
+		if err != nil {
+			if err = m.handleError(&errs, nestedError(err, "["+strconv.Itoa(i)+"]", strconv.Itoa(i), i)); err != nil {
+				return err
+			}
 		}
-		err = unmarshalStruct([]byte(value.String()), newObj)
+	}
+	return errs.err()
+}
+
+func (m *Mapper) unmarshalMap(data []byte, mapValue reflect.Value) (err error) {
+	result := gjson.ParseBytes(data)
+	if result.Type == gjson.Null {
+		mapValue.Set(reflect.Zero(mapValue.Type()))
+		return nil
+	}
+	if !result.IsObject() {
+		return errorx.IllegalArgument.New("cannot unmarshal %s into %s", result.Type, mapValue.Type())
+	}
+	if mapValue.IsNil() {
+		mapValue.Set(reflect.MakeMap(mapValue.Type()))
+	}
+	mapType := mapValue.Type()
+	errs := MappingErrors{}
+	result.ForEach(func(key, element gjson.Result) bool {
+		var keyValue reflect.Value
+		keyValue, err = decodeMapKey(key.String(), mapType)
 		if err != nil {
 			return false
 		}
-		if sliceObjType.Kind() == reflect.Struct && reflect.TypeOf(newObj).Kind() == reflect.Ptr {
-			appendToSlice(v, reflect.ValueOf(newObj).Elem().Interface())
-		} else {
-			appendToSlice(v, reflect.ValueOf(newObj).Interface())
+		elemValue := reflect.New(mapType.Elem()).Elem()
+		err = m.unmarshalValue([]byte(element.Raw), elemValue)
+		if err != nil {
+			err = m.handleError(&errs, nestedError(err, "["+strconv.Quote(key.String())+"]", escapePathKey(key.String()), -1))
+			return err == nil
 		}
+		mapValue.SetMapIndex(keyValue, elemValue)
 		return true
 	})
-	return
+	if err != nil {
+		return err
+	}
+	return errs.err()
+}
+
+// decodeMapKey decodes an object key into a key of mapType the way encoding/json does: text unmarshalers first,
+// then strings, then integers
+func decodeMapKey(key string, mapType reflect.Type) (reflect.Value, error) {
+	typ := mapType.Key()
+	keyValue := reflect.New(typ)
+	if unmarshaler, ok := keyValue.Interface().(encoding.TextUnmarshaler); ok {
+		err := unmarshaler.UnmarshalText([]byte(key))
+		return keyValue.Elem(), err
+	}
+	keyValue = keyValue.Elem()
+	switch typ.Kind() {
+	case reflect.String:
+		keyValue.SetString(key)
+		return keyValue, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(key, 10, 64)
+		if err != nil || keyValue.OverflowInt(n) {
+			return keyValue, &json.UnmarshalTypeError{Value: "number " + key, Type: typ}
+		}
+		keyValue.SetInt(n)
+		return keyValue, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(key, 10, 64)
+		if err != nil || keyValue.OverflowUint(n) {
+			return keyValue, &json.UnmarshalTypeError{Value: "number " + key, Type: typ}
+		}
+		keyValue.SetUint(n)
+		return keyValue, nil
+	}
+	return keyValue, &json.UnmarshalTypeError{Value: "object", Type: mapType}
 }
 
-func unmarshalStruct(data []byte, v any) error {
+func (m *Mapper) unmarshalStruct(data []byte, structValue reflect.Value) error {
+	if generated, ok := m.generatedUnmarshaler(structValue); ok {
+		return generated.UnmarshalMapped(m, data)
+	}
 	// read tags
-	tagDatas, err := getTagDatas(v)
-	if err != nil {
-		return err
+	plan := getTypePlan(structValue.Type(), m.tags)
+	errs := MappingErrors{}
+	// the keys read by mapped paths, kept to find the unknown ones
+	source := data
+	consumed := [][]string{}
+
+	// every missing required path is reported together, before anything is decoded
+	missing := MappingErrors{}
+	for _, tagData := range plan.Fields {
+		if !tagData.Required || tagData.WriteOnly || tagData.PathError != nil {
+			continue
+		}
+		result, path := tagData.lookup(data, m.foldCase)
+		if !result.Exists() || result.Type == gjson.Null {
+			missing.add(fieldError(RequiredFieldMissing.New("required path %s is missing", tagData.mapperPath(unmarshalling)), tagData, path, unmarshalling))
+		}
+	}
+	if len(missing) > 0 {
+		if err := m.handleError(&errs, missing); err != nil {
+			return err
+		}
+		return errs.err()
 	}
 
+	changes := []change{}
 	// process any fields that have the mapper tag, track updates in case there is collision on tags
-	if len(tagDatas) > 0 {
-		changes := []change{}
-		for _, tagData := range tagDatas {
+	if len(plan.Fields) > 0 {
+		for _, tagData := range plan.Fields {
+			if tagData.WriteOnly {
+				continue
+			}
+			if tagData.PathError != nil {
+				if err := m.handleError(&errs, fieldError(tagData.PathError, tagData, tagData.MapperFieldPath, unmarshalling)); err != nil {
+					return err
+				}
+				continue
+			}
 			// get the value using the mapped path
-			value, err := getValue(data, tagData.MapperFieldPath, tagData.Coerce, tagData.AsString, tagData.Field)
+			result, path := tagData.lookup(data, m.foldCase)
+			var value string
+			var err error
+			if tagData.Default != nil && (!result.Exists() || result.Type == gjson.Null) {
+				// defaults are written as text in the tag, they always need converting to the field's type
+				value, err = m.getCoercedValue(*tagData.Default, tagData, unmarshalling)
+			} else if !result.Exists() {
+				// nothing to copy, writing an empty value would break the document
+				continue
+			} else {
+				value, err = m.getValue(result, tagData, unmarshalling)
+			}
+			if (m.unknownFields != IgnoreUnknownFields || plan.Remain != nil) && result.Exists() {
+				consumed = append(consumed, consumedPaths(source, result, path)...)
+			}
 			if err != nil {
-				return err
+				if err = m.handleError(&errs, fieldError(err, tagData, path, unmarshalling)); err != nil {
+					return err
+				}
+				continue
 			}
 			if tagData.OmitEmpty && isEmptyValue(value) {
 				continue
 			}
-			changes = append(changes, change{Path: tagData.JsonFieldName, Value: []byte(value)})
+			changes = append(changes, change{Path: escapePathKey(tagData.JsonFieldName), Value: []byte(value), TagData: tagData})
 		}
+	}
+
+	// fields known by another name are decoded from their document keys, not their json keys
+	for _, renamed := range plan.Renamed {
+		var err error
+		data, err = sjson.DeleteBytes(data, escapePathKey(renamed.JsonFieldName))
+		if err == nil {
+			data, err = moveKey(data, renamed.DocumentKey, renamed.JsonFieldName)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(changes) > 0 {
 		// apply updates
 		for _, change := range changes {
 			// set the value to the field's json path
+			var err error
 			data, err = sjson.SetRawBytes(data, change.Path, change.Value)
 			if err != nil {
+				if err = m.handleError(&errs, fieldError(err, change.TagData, change.Path, unmarshalling)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if plan.Remain != nil {
+		remained, err := fillRemain(source, data, plan, consumed)
+		if err == nil {
+			data = remained
+		} else {
+			if err = m.handleError(&errs, fieldError(err, *plan.Remain, plan.Remain.MapperFieldPath, unmarshalling)); err != nil {
 				return err
 			}
 		}
 	}
-	err = json.Unmarshal(data, v)
-	return err
-}
 
-func getTagDatas(v any) ([]tagInfo, error) {
-	// map the marshal fields
-	destType := reflect.TypeOf(v)
-	if destType.Kind() == reflect.Ptr {
-		// if it's a pointer, get the non pointer type
-		destType = destType.Elem()
+	// pull nested values out of the document, they are unmarshaled with their own tags afterwards
+	nestedData := make([][]byte, len(plan.Nested))
+	for i, nested := range plan.Nested {
+		key, ok := decodedKey(data, plan, nested)
+		if !ok {
+			continue
+		}
+		jsonPath := escapePathKey(key)
+		nestedData[i] = []byte(gjson.GetBytes(data, jsonPath).Raw)
+		var err error
+		data, err = sjson.DeleteBytes(data, jsonPath)
+		if err != nil {
+			return err
+		}
 	}
 
-	// Iterate over all available fields and read the tag values
-	tagDatas := []tagInfo{}
-	if destType.Kind() == reflect.Struct {
-		for i := 0; i < destType.NumField(); i++ {
-			field := destType.Field(i)
-			if field.Tag.Get(mapperTagName) != "" {
-				tagData := getTagInfo(field)
-				if tagData.MapperFieldPath != "" || tagData.OmitEmpty == true {
-					tagDatas = append(tagDatas, tagData)
-				}
+	err := json.Unmarshal(data, structValue.Addr().Interface())
+	if err != nil {
+		if err = m.handleError(&errs, unmarshalError(err, plan)); err != nil {
+			return err
+		}
+	}
+	for i, nested := range plan.Nested {
+		if nestedData[i] == nil {
+			continue
+		}
+		fieldValue, err := fieldForSet(structValue, nested.Field.Index)
+		if err == nil {
+			err = m.unmarshalValue(nestedData[i], fieldValue)
+		}
+		if err != nil {
+			if err = m.handleError(&errs, nestedError(err, nested.Field.Name, nested.outputPath(unmarshalling), -1)); err != nil {
+				return err
 			}
 		}
 	}
-
-	return tagDatas, nil
+	if m.unknownFields != IgnoreUnknownFields {
+		if unknown := m.unknownInStruct(gjson.ParseBytes(source), plan, consumed); len(unknown) > 0 {
+			if err := m.handleError(&errs, unknown); err != nil {
+				return err
+			}
+		}
+	}
+	return errs.err()
 }
 
-func getValue(data []byte, path string, coerce, asString bool, field reflect.StructField) (string, error) {
+func (m *Mapper) getValue(result gjson.Result, tagData tagInfo, dir direction) (string, error) {
 	var value string
 	var err error
-	result := gjson.GetBytes(data, path)
-	if coerce {
-		value, err = getCoercedValue(result, field)
-	} else if asString {
+	coerced := tagData.Coerce || m.defaultCoercion
+	// without coercion the inverses run before the value is read as a string, which may leave text that isn't json
+	if len(tagData.Transforms) > 0 && (dir == unmarshalling || !coerced && tagData.AsString) {
+		result, err = m.applyTransforms(result, tagData, dir)
+		if err != nil {
+			return "", err
+		}
+	}
+	if coerced {
+		value, err = m.getCoercedValue(result, tagData, dir)
+	} else if tagData.AsString {
 		value = result.String()
 	} else {
 		value = result.Raw
 	}
+	if err == nil && len(tagData.Transforms) > 0 && dir == marshalling && (coerced || !tagData.AsString) && gjson.Valid(value) {
+		// marshalling mirrors unmarshalling, the inverses undo the transforms after coercion
+		result, err = m.applyTransforms(gjson.Parse(value), tagData, dir)
+		value = result.Raw
+	}
 	return value, err
 }
 
-func getCoercedValue(result gjson.Result, field reflect.StructField) (string, error) {
+func (m *Mapper) getCoercedValue(result gjson.Result, tagData tagInfo, dir direction) (string, error) {
+	typ := coercedType(tagData.Field.Type)
+	if tagData.Projected && typ.Kind() == reflect.Slice && result.IsArray() {
+		return m.coerceElements(result, tagData, typ.Elem(), dir)
+	}
+	return m.coerceValue(result, tagData, typ, dir)
+}
+
+// coercedType returns the type values of a field of type typ are coerced to, pointers are coerced to what they point to
+func coercedType(typ reflect.Type) reflect.Type {
+	if typ.Kind() == reflect.Ptr {
+		return typ.Elem()
+	}
+	return typ
+}
+
+// coerceElements coerces every element of a projected array to the element type of the field's slice
+func (m *Mapper) coerceElements(result gjson.Result, tagData tagInfo, elemType reflect.Type, dir direction) (string, error) {
+	elemType = coercedType(elemType)
+	elements := []string{}
+	var err error
+	result.ForEach(func(_, element gjson.Result) bool {
+		var value string
+		value, err = m.coerceValue(element, tagData, elemType, dir)
+		if err != nil {
+			err = errorx.Decorate(err, "element %d", len(elements))
+			return false
+		}
+		elements = append(elements, value)
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+	return "[" + strings.Join(elements, ",") + "]", nil
+}
+
+// coerceValue converts result to typ, which is the field's type or, for projections, its element type
+func (m *Mapper) coerceValue(result gjson.Result, tagData tagInfo, typ reflect.Type, dir direction) (string, error) {
 	var rawValue interface{}
 	var err error
-	typ := field.Type
-	if typ.Kind() == reflect.Ptr {
-		typ = typ.Elem()
+	if converter, ok := m.getConverter(result.Type, typ); ok {
+		return encodeCoerced(converter(result))
+	}
+	switch typ {
+	case timeType:
+		return encodeCoerced(coerceTime(result, tagData, dir))
+	case durationType:
+		return encodeCoerced(coerceDuration(result, tagData, dir))
+	}
+	if tagData.Strict || m.strictCoercion {
+		if result.Type == gjson.Null {
+			// nothing to convert, leave the field alone the way encoding/json does
+			return "null", nil
+		}
+		if value, ok, err := strictCoerce(result, typ); ok {
+			return encodeCoerced(value, err)
+		}
 	}
 	switch typ.Kind() {
 	case reflect.String:
@@ -274,11 +959,21 @@ func getCoercedValue(result gjson.Result, field reflect.StructField) (string, er
 	return string(jsonBytes), err
 }
 
-func getTagInfo(field reflect.StructField) tagInfo {
+// encodeCoerced json encodes the result of a conversion
+func encodeCoerced(rawValue any, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	jsonBytes, err := json.Marshal(rawValue)
+	return string(jsonBytes), err
+}
+
+func getTagInfo(field reflect.StructField, tags tagNames) tagInfo {
 	tagData := tagInfo{
 		Field: field,
 	}
-	mapperTagSplit := strings.Split(field.Tag.Get(mapperTagName), ",")
+	// commas inside brackets and quotes belong to the part, e.g. transform=split(,)
+	mapperTagSplit := splitOutside(field.Tag.Get(tags.Mapper), ',')
 	for _, tagPart := range mapperTagSplit {
 		if tagPart == asString {
 			tagData.AsString = true
@@ -286,49 +981,133 @@ func getTagInfo(field reflect.StructField) tagInfo {
 			tagData.OmitEmpty = true
 		} else if tagPart == coerce {
 			tagData.Coerce = true
+		} else if tagPart == required {
+			tagData.Required = true
+		} else if tagPart == strict {
+			// strict is a kind of coercion, there is no point in one without the other
+			tagData.Strict = true
+			tagData.Coerce = true
+		} else if strings.HasPrefix(tagPart, layoutPrefix) {
+			tagData.Layout = strings.TrimPrefix(tagPart, layoutPrefix)
+		} else if strings.HasPrefix(tagPart, defaultPrefix) {
+			tagData.Default = parseDefault(strings.TrimPrefix(tagPart, defaultPrefix))
+		} else if strings.HasPrefix(tagPart, tzPrefix) {
+			tagData.TimeZone = strings.TrimPrefix(tagPart, tzPrefix)
+			tagData.Location, _ = time.LoadLocation(tagData.TimeZone)
+		} else if tagPart == readOnly {
+			tagData.ReadOnly = true
+		} else if tagPart == writeOnly {
+			tagData.WriteOnly = true
+		} else if tagPart == move {
+			tagData.Move = true
+		} else if tagPart == copyOption {
+			tagData.Copy = true
+		} else if tagPart == remain {
+			tagData.Remain = true
+		} else if strings.HasPrefix(tagPart, transformPrefix) {
+			tagData.Transforms = parseTransforms(strings.TrimPrefix(tagPart, transformPrefix))
+		} else if strings.HasPrefix(tagPart, outPrefix) {
+			tagData.OutPath = strings.TrimPrefix(tagPart, outPrefix)
 		} else {
-			tagData.MapperFieldPath = tagPart
+			// alternative paths are separated by a pipe, the first one is also the one written to
+			paths := splitOutside(strings.TrimPrefix(tagPart, inPrefix), '|')
+			tagData.MapperFieldPath = paths[0]
+			tagData.FallbackPaths = paths[1:]
 		}
 	}
 	jsonTagSplit := strings.Split(field.Tag.Get(jsonTagName), ",")
-	if len(jsonTagSplit) > 0 {
+	if jsonTagSplit[0] != "" {
 		tagData.JsonFieldName = jsonTagSplit[0]
 	} else {
 		tagData.JsonFieldName = field.Name
 	}
+	tagData.DocumentKey = documentKey(field, tagData.JsonFieldName, tags.JSON)
 	if tagData.MapperFieldPath == "" {
-		tagData.MapperFieldPath = tagData.JsonFieldName
+		// without a path the field is mapped to its own key, which isn't path syntax
+		tagData.MapperFieldPath = escapePathKey(tagData.DocumentKey)
+	}
+	if tagData.OutPath == "" {
+		tagData.OutPath = tagData.MapperFieldPath
 	}
+	compilePaths(&tagData)
 	return tagData
 }
 
-func isSlice(v any) bool {
-	_, typ := getValueAndType(v)
-	return typ.Kind() == reflect.Slice
+// compilePaths checks the field's paths against the path grammar, and works out the gjson paths they are read from
+func compilePaths(tagData *tagInfo) {
+	for _, path := range append([]string{tagData.MapperFieldPath}, tagData.FallbackPaths...) {
+		compiled, err := compilePath(path)
+		if err != nil && tagData.PathError == nil {
+			tagData.PathError = err
+		}
+		tagData.ReadPaths = append(tagData.ReadPaths, compiled.Read)
+		tagData.Projected = tagData.Projected || isProjection(path)
+	}
+	compiled, err := compilePath(tagData.OutPath)
+	if err != nil {
+		tagData.OutPathError = err
+	} else if !compiled.Writable {
+		tagData.OutPathError = InvalidPath.New("path %s can only be read, give the field an out= path or make it readonly", tagData.OutPath)
+	}
+	tagData.OutProjection = compiled.Projection
+	tagData.Projected = tagData.Projected || compiled.Projection != nil
 }
 
-func isStruct(v any) bool {
-	_, typ := getValueAndType(v)
-	return typ.Kind() == reflect.Struct
+// parseDefault reads the default from a mapper tag as json, anything that isn't valid json is taken as a string
+func parseDefault(value string) *gjson.Result {
+	if gjson.Valid(value) {
+		result := gjson.Parse(value)
+		return &result
+	}
+	raw, _ := json.Marshal(value)
+	return &gjson.Result{Type: gjson.String, Str: value, Raw: string(raw)}
 }
-func getValueAndType(v any) (value reflect.Value, typ reflect.Type) {
-	typ = reflect.TypeOf(v)
-	value = reflect.ValueOf(v)
-	if typ.Kind() == reflect.Ptr {
-		typ = typ.Elem()
-		value = value.Elem()
+
+// lookup reads the first of the field's mapped paths that holds a value that isn't null, and returns the path it was found at
+func (t tagInfo) lookup(data []byte, foldCase bool) (gjson.Result, string) {
+	result := getPath(data, t.ReadPaths[0], foldCase)
+	if len(t.FallbackPaths) == 0 || result.Exists() && result.Type != gjson.Null {
+		return result, t.MapperFieldPath
+	}
+	found, foundPath := result, t.MapperFieldPath
+	for i, path := range t.FallbackPaths {
+		fallback := getPath(data, t.ReadPaths[i+1], foldCase)
+		if fallback.Exists() && fallback.Type != gjson.Null {
+			return fallback, path
+		}
+		if !found.Exists() && fallback.Exists() {
+			// an explicit null still beats a missing path
+			found, foundPath = fallback, path
+		}
 	}
-	return
+	return found, foundPath
 }
 
-func appendToSlice(arrPtr, toAppend interface{}) {
-	valuePtr := reflect.ValueOf(arrPtr)
-	value := valuePtr.Elem()
-	value.Set(reflect.Append(value, reflect.ValueOf(toAppend)))
+// mapperPath is the path as written in the mapper tag for dir, including any fallbacks
+func (t tagInfo) mapperPath(dir direction) string {
+	if dir == marshalling {
+		return t.OutPath
+	}
+	return strings.Join(append([]string{t.MapperFieldPath}, t.FallbackPaths...), "|")
+}
+
+// outputPath is where the field's value sits in the mapped json, its mapper path if it has one or its json key otherwise
+func (t tagInfo) outputPath(dir direction) string {
+	if t.MapperFieldPath == "" {
+		return escapePathKey(t.DocumentKey)
+	}
+	if dir == marshalling {
+		return t.OutPath
+	}
+	return t.MapperFieldPath
 }
 
 func isEmptyValue(value interface{}) bool {
-	v := reflect.ValueOf(value)
+	return isEmptyReflectValue(reflect.ValueOf(value))
+}
+
+// isEmptyReflectValue reports whether encoding/json's omitempty leaves v out
+func isEmptyReflectValue(v reflect.Value) bool {
 	switch v.Kind() {
 	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
 		return v.Len() == 0
@@ -345,3 +1124,149 @@ func isEmptyValue(value interface{}) bool {
 	}
 	return false
 }
+
+// fieldForSet returns the field of structValue at index, allocating nil embedded struct pointers on the way the same way encoding/json does
+func fieldForSet(structValue reflect.Value, index []int) (reflect.Value, error) {
+	value := structValue
+	for i, fieldIndex := range index {
+		if i > 0 && value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				if !value.CanSet() {
+					return reflect.Value{}, errorx.IllegalArgument.New("cannot set embedded pointer to unexported struct: %v", value.Type().Elem())
+				}
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		value = value.Field(fieldIndex)
+	}
+	return value, nil
+}
+
+func isNull(data []byte) bool {
+	return gjson.ParseBytes(data).Type == gjson.Null
+}
+
+// getPath reads path from data. With foldCase, object keys that don't match exactly are matched ignoring case.
+func getPath(data []byte, path string, foldCase bool) gjson.Result {
+	result := gjson.GetBytes(data, path)
+	if result.Exists() || !foldCase {
+		return result
+	}
+	result = gjson.ParseBytes(data)
+	for _, part := range splitOutside(path, '.') {
+		next := result.Get(part)
+		if !next.Exists() && result.IsObject() {
+			key := unescapePathKey(part)
+			result.ForEach(func(name, value gjson.Result) bool {
+				if strings.EqualFold(name.String(), key) {
+					next = value
+					return false
+				}
+				return true
+			})
+		}
+		if !next.Exists() {
+			return next
+		}
+		result = next
+	}
+	return result
+}
+
+// decodedKey finds the key of the object in data that encoding/json decodes into field: its json key, or else a key
+// matching it ignoring case that isn't the json key of another field
+func decodedKey(data []byte, plan *typePlan, field tagInfo) (string, bool) {
+	if gjson.GetBytes(data, escapePathKey(field.JsonFieldName)).Exists() {
+		return field.JsonFieldName, true
+	}
+	found, ok := "", false
+	gjson.ParseBytes(data).ForEach(func(key, _ gjson.Result) bool {
+		name := key.String()
+		if !strings.EqualFold(name, field.JsonFieldName) {
+			return true
+		}
+		for _, other := range plan.Visible {
+			if other.JsonFieldName == name {
+				return true
+			}
+		}
+		// encoding/json keeps the last of the keys it decodes into the same field
+		found, ok = name, true
+		return true
+	})
+	return found, ok
+}
+
+// moveKey moves the value at the object key from to the key to, it does nothing when from doesn't exist
+func moveKey(data []byte, from, to string) ([]byte, error) {
+	result := gjson.GetBytes(data, escapePathKey(from))
+	if !result.Exists() {
+		return data, nil
+	}
+	data, err := sjson.DeleteBytes(data, escapePathKey(from))
+	if err != nil {
+		return nil, err
+	}
+	return sjson.SetRawBytes(data, escapePathKey(to), []byte(result.Raw))
+}
+
+// splitOutside splits s at every sep that isn't escaped, quoted or inside brackets, so gjson queries
+// and modifier arguments stay in one piece
+func splitOutside(s string, sep byte) []string {
+	parts := []string{}
+	depth := 0
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// escapePathKey escapes a single object key so gjson and sjson don't read it as path syntax
+func escapePathKey(key string) string {
+	escaped := make([]byte, 0, len(key))
+	for i := 0; i < len(key); i++ {
+		if !isSafePathKeyChar(key[i]) {
+			escaped = append(escaped, '\\')
+		}
+		escaped = append(escaped, key[i])
+	}
+	return string(escaped)
+}
+
+// unescapePathKey reverses escapePathKey
+func unescapePathKey(key string) string {
+	if !strings.Contains(key, "\\") {
+		return key
+	}
+	var builder strings.Builder
+	for i := 0; i < len(key); i++ {
+		if key[i] == '\\' && i+1 < len(key) {
+			i++
+		}
+		builder.WriteByte(key[i])
+	}
+	return builder.String()
+}
+
+// isSafePathKeyChar matches the characters gjson leaves unescaped in a path component
+func isSafePathKeyChar(c byte) bool {
+	return c <= ' ' || c > '~' || c == '_' || c == '-' || c == ':' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9')
+}
@@ -0,0 +1,168 @@
+package pkg
+
+import (
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"strconv"
+	"strings"
+)
+
+// appendIndex is the path component that appends to an array when writing, and reads its last element
+const appendIndex = "-1"
+
+// compiledPath is a mapper path checked against the path grammar.
+//
+// Every path can be read. Object keys, array indexes and -1 can also be written, and so can a projection like
+// items.#.id that has a single # between them. gjson queries (#(...) and #(...)#), any other use of #,
+// wildcards, multipaths and @modifiers can only be read.
+type compiledPath struct {
+	// Read is the gjson path the value is read from, it differs from the path in the tag when the path uses -1
+	Read     string
+	Writable bool
+	// Projection is set for paths that project a key out of every element of an array
+	Projection *projection
+}
+
+// projection splits a path like items.#.id into the path of the array, items, and the path within each element, id
+type projection struct {
+	Array   string
+	Element string
+}
+
+// compilePath checks path against the path grammar
+func compilePath(path string) (compiledPath, error) {
+	if path == "" {
+		return compiledPath{}, InvalidPath.New("empty path")
+	}
+	if err := checkBalanced(path); err != nil {
+		return compiledPath{}, err
+	}
+	compiled := compiledPath{Writable: true}
+	components := splitOutside(path, '.')
+	written := append([]string{}, components...)
+	projectAt := -1
+	for i, component := range components {
+		switch {
+		case component == "":
+			return compiledPath{}, InvalidPath.New("path %s has an empty key", path)
+		case component == appendIndex:
+			// gjson has no negative indexes, the last element is the first one of the reversed array
+			components[i] = "@reverse.0"
+		case component == "#":
+			if projectAt != -1 || i == 0 || i == len(components)-1 {
+				// counts, projections of projections and root arrays can only be read
+				compiled.Writable = false
+			}
+			projectAt = i
+		case strings.HasPrefix(component, "#("):
+			if !strings.HasSuffix(component, ")") && !strings.HasSuffix(component, ")#") {
+				return compiledPath{}, InvalidPath.New("path %s has a malformed query %s", path, component)
+			}
+			compiled.Writable = false
+		case component[0] == '@':
+			name, _, _ := strings.Cut(component[1:], ":")
+			if !gjson.ModifierExists(name, nil) {
+				return compiledPath{}, InvalidPath.New("path %s uses the unknown modifier @%s", path, name)
+			}
+			compiled.Writable = false
+		case component[0] == '{' || component[0] == '[' || component[0] == '!':
+			compiled.Writable = false
+		case hasWildcard(component):
+			compiled.Writable = false
+		}
+	}
+	compiled.Read = strings.Join(components, ".")
+	if compiled.Writable && projectAt != -1 {
+		compiled.Projection = &projection{
+			Array:   strings.Join(written[:projectAt], "."),
+			Element: strings.Join(written[projectAt+1:], "."),
+		}
+	}
+	return compiled, nil
+}
+
+// isProjection reports whether path reads a value from every element of an array, with # or a #(...)# query
+func isProjection(path string) bool {
+	for _, component := range splitOutside(path, '.') {
+		if component == "#" && !strings.HasSuffix(path, ".#") || strings.HasPrefix(component, "#(") && strings.HasSuffix(component, ")#") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBalanced reports brackets and quotes in path that are never closed
+func checkBalanced(path string) error {
+	closing := []byte{}
+	quoted := false
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			closing = append(closing, ')')
+		case c == '[':
+			closing = append(closing, ']')
+		case c == '{':
+			closing = append(closing, '}')
+		case c == ')' || c == ']' || c == '}':
+			if len(closing) == 0 || closing[len(closing)-1] != c {
+				return InvalidPath.New("path %s has an unexpected %c", path, c)
+			}
+			closing = closing[:len(closing)-1]
+		}
+	}
+	if quoted {
+		return InvalidPath.New("path %s has an unterminated string", path)
+	}
+	if len(closing) > 0 {
+		return InvalidPath.New("path %s is missing a %c", path, closing[len(closing)-1])
+	}
+	return nil
+}
+
+// hasWildcard reports whether a path component has an unescaped * or ?
+func hasWildcard(component string) bool {
+	for i := 0; i < len(component); i++ {
+		switch component[i] {
+		case '\\':
+			i++
+		case '*', '?':
+			return true
+		}
+	}
+	return false
+}
+
+// setPath writes the raw json value at path. For projections the value has to be an array, and each of its
+// elements is written into the element of the projected array at the same index.
+func setPath(data []byte, path string, projected *projection, value []byte) ([]byte, error) {
+	if projected == nil {
+		return sjson.SetRawBytes(data, path, value)
+	}
+	elements := gjson.ParseBytes(value)
+	if elements.Type == gjson.Null {
+		return data, nil
+	}
+	if !elements.IsArray() {
+		return nil, InvalidPath.New("path %s projects an array, got %s", path, elements.Raw)
+	}
+	if !gjson.GetBytes(data, projected.Array).Exists() {
+		var err error
+		data, err = sjson.SetRawBytes(data, projected.Array, []byte("[]"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	for i, element := range elements.Array() {
+		data, err = sjson.SetRawBytes(data, projected.Array+"."+strconv.Itoa(i)+"."+projected.Element, []byte(element.Raw))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,270 @@
+package pkg
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// typePlan is the compiled mapping information for a single struct type.
+// Plans are built once per type and reused for every Marshal/Unmarshal call.
+type typePlan struct {
+	// Fields have a mapper tag of their own
+	Fields []tagInfo
+	// Nested fields hold a struct, or a pointer, slice or map of structs, that needs mapping itself
+	Nested []tagInfo
+	// Visible has every field encoding/json sees, MapperFieldPath is only set on the ones with a mapper tag
+	Visible []tagInfo
+	// Renamed fields have a DocumentKey that isn't their json key
+	Renamed []tagInfo
+	// Remain is the field that collects the keys no other field reads, if the type has one
+	Remain *tagInfo
+	// Coerced are the types coerced fields are converted to, the ones converters can be registered for
+	Coerced []reflect.Type
+}
+
+// tagNames are the struct tags a Mapper reads mapped paths and document keys from
+type tagNames struct {
+	Mapper string
+	JSON   string
+}
+
+// planKey identifies a plan, the same type has a plan for every set of tags it is mapped with
+type planKey struct {
+	Type reflect.Type
+	Tags tagNames
+}
+
+var (
+	// planCache holds a *typePlan for every reflect.Type and tag name that has been mapped so far
+	planCache sync.Map
+	// needsMappingCache remembers whether tags can be found anywhere inside a reflect.Type
+	needsMappingCache sync.Map
+
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// getTypePlan returns the cached plan for typ mapped with tags, compiling it on first use
+func getTypePlan(typ reflect.Type, tags tagNames) *typePlan {
+	key := planKey{Type: typ, Tags: tags}
+	if cached, ok := planCache.Load(key); ok {
+		return cached.(*typePlan)
+	}
+	plan := compileTypePlan(typ, tags)
+	// another goroutine may have compiled the same type in the meantime, keep whichever was stored first
+	actual, _ := planCache.LoadOrStore(key, plan)
+	return actual.(*typePlan)
+}
+
+func compileTypePlan(typ reflect.Type, tags tagNames) *typePlan {
+	plan := &typePlan{Fields: []tagInfo{}, Nested: []tagInfo{}, Visible: []tagInfo{}, Renamed: []tagInfo{}}
+	for _, field := range visibleFields(typ) {
+		name, _ := jsonFieldName(field)
+		fieldInfo := tagInfo{Field: field, JsonFieldName: name, DocumentKey: documentKey(field, name, tags.JSON)}
+		if field.Tag.Get(tags.Mapper) != "" {
+			tagData := getTagInfo(field, tags)
+			if tagData.Remain {
+				// the remain field isn't mapped itself, it collects what the other fields don't read
+				if plan.Remain == nil {
+					plan.Remain = &tagData
+				}
+				fieldInfo = tagData
+			} else if tagData.MapperFieldPath != "" || tagData.OmitEmpty == true {
+				plan.Fields = append(plan.Fields, tagData)
+				fieldInfo = tagData
+				if tagData.Coerce {
+					typ := coercedType(field.Type)
+					plan.Coerced = append(plan.Coerced, typ)
+					if tagData.Projected && typ.Kind() == reflect.Slice {
+						plan.Coerced = append(plan.Coerced, coercedType(typ.Elem()))
+					}
+				}
+			}
+		}
+		plan.Visible = append(plan.Visible, fieldInfo)
+		if fieldInfo.DocumentKey != fieldInfo.JsonFieldName {
+			plan.Renamed = append(plan.Renamed, fieldInfo)
+		}
+		if typeNeedsMapping(field.Type, tags) {
+			plan.Nested = append(plan.Nested, fieldInfo)
+		}
+	}
+	return plan
+}
+
+// visibleFields returns the fields encoding/json would encode for typ, including the ones promoted from
+// embedded structs. Promotion follows the same visibility and shadowing rules as encoding/json, and the
+// Index of every returned field is the full index path from typ.
+func visibleFields(typ reflect.Type) []reflect.StructField {
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+	candidates := []fieldCandidate{}
+	current := []reflect.StructField{{Type: typ}}
+	// structs already walked at a shallower depth, their fields would be shadowed anyway
+	visited := map[reflect.Type]bool{}
+	// walk one embedding depth at a time so shallower fields come first
+	for len(current) > 0 {
+		next := []reflect.StructField{}
+		walked := map[reflect.Type]bool{}
+		for _, parent := range current {
+			parentType := parent.Type
+			if parentType.Kind() == reflect.Ptr {
+				parentType = parentType.Elem()
+			}
+			if visited[parentType] {
+				continue
+			}
+			walked[parentType] = true
+			for i := 0; i < parentType.NumField(); i++ {
+				field := parentType.Field(i)
+				field.Index = append(append([]int{}, parent.Index...), i)
+				fieldType := field.Type
+				if fieldType.Kind() == reflect.Ptr {
+					fieldType = fieldType.Elem()
+				}
+				if field.Anonymous {
+					// unexported embedded structs still promote their exported fields
+					if !field.IsExported() && fieldType.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !field.IsExported() {
+					continue
+				}
+				name, ok := jsonFieldName(field)
+				if !ok {
+					continue
+				}
+				tagged := strings.Split(field.Tag.Get(jsonTagName), ",")[0] != ""
+				if !field.Anonymous || tagged || fieldType.Kind() != reflect.Struct {
+					candidates = append(candidates, fieldCandidate{Field: field, Name: name, Tagged: tagged})
+					continue
+				}
+				next = append(next, field)
+			}
+		}
+		for walkedType := range walked {
+			visited[walkedType] = true
+		}
+		current = next
+	}
+
+	byName := map[string][]fieldCandidate{}
+	for _, candidate := range candidates {
+		byName[candidate.Name] = append(byName[candidate.Name], candidate)
+	}
+	fields := []reflect.StructField{}
+	for _, candidate := range candidates {
+		dominant, ok := dominantField(byName[candidate.Name])
+		if ok && reflect.DeepEqual(dominant.Index, candidate.Field.Index) {
+			fields = append(fields, candidate.Field)
+		}
+	}
+	return fields
+}
+
+type fieldCandidate struct {
+	Field  reflect.StructField
+	Name   string
+	Tagged bool
+}
+
+// dominantField picks the field that wins when several fields share a json name. The shallowest one wins,
+// ties are broken by a json tag, and if that still doesn't settle it none of them are used.
+// The candidates are expected in the order visibleFields found them, shallowest first.
+func dominantField(candidates []fieldCandidate) (reflect.StructField, bool) {
+	depth := len(candidates[0].Field.Index)
+	var dominant *fieldCandidate
+	conflict := false
+	for i := range candidates {
+		candidate := &candidates[i]
+		if len(candidate.Field.Index) > depth {
+			break
+		}
+		switch {
+		case dominant == nil:
+			dominant = candidate
+		case candidate.Tagged && !dominant.Tagged:
+			dominant = candidate
+			conflict = false
+		case candidate.Tagged == dominant.Tagged:
+			conflict = true
+		}
+	}
+	if conflict {
+		return reflect.StructField{}, false
+	}
+	return dominant.Field, true
+}
+
+// typeNeedsMapping reports whether typ, or anything reachable through its fields, elements and pointers, has mapper
+// tags, hooks, or document keys that differ from its json keys. Interfaces may hold anything, so they always need mapping. Values of types that don't need mapping are handed to encoding/json as-is.
+func typeNeedsMapping(typ reflect.Type, tags tagNames) bool {
+	key := planKey{Type: typ, Tags: tags}
+	if cached, ok := needsMappingCache.Load(key); ok {
+		return cached.(bool)
+	}
+	needsMapping := searchForMapping(typ, tags, map[reflect.Type]bool{})
+	needsMappingCache.Store(key, needsMapping)
+	return needsMapping
+}
+
+func searchForMapping(typ reflect.Type, tags tagNames, visiting map[reflect.Type]bool) bool {
+	// types with hooks take part in mapping, other types with their own json encoding are left alone
+	if getTypeHooks(typ).any() {
+		return true
+	}
+	if hasJSONEncoding(typ) {
+		return false
+	}
+	switch typ.Kind() {
+	case reflect.Interface:
+		// the value inside decides, it's looked at when the value is mapped
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return searchForMapping(typ.Elem(), tags, visiting)
+	case reflect.Struct:
+		// recursive types are answered by the outermost call
+		if visiting[typ] {
+			return false
+		}
+		visiting[typ] = true
+		for _, field := range visibleFields(typ) {
+			if field.Tag.Get(tags.Mapper) != "" || searchForMapping(field.Type, tags, visiting) {
+				return true
+			}
+			if name, ok := jsonFieldName(field); ok && documentKey(field, name, tags.JSON) != name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jsonFieldName returns the key encoding/json uses for field, and false if the field is skipped by encoding/json
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	jsonTag := field.Tag.Get(jsonTagName)
+	if jsonTag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(jsonTag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+// documentKey returns the key field has in mapped documents. That is its json key, unless the Mapper reads keys from
+// another tag and field has that tag.
+func documentKey(field reflect.StructField, jsonName, tagName string) string {
+	if tagName == jsonTagName {
+		return jsonName
+	}
+	name, _, _ := strings.Cut(field.Tag.Get(tagName), ",")
+	if name == "" || name == "-" {
+		return jsonName
+	}
+	return name
+}
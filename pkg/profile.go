@@ -0,0 +1,45 @@
+package pkg
+
+// profileTagName returns the struct tag used by the named profile, so the "stripe" profile of the mapper tag
+// uses `mapper_stripe:"..."` tags. The empty profile uses tagName itself.
+func profileTagName(tagName, profile string) string {
+	if profile == "" {
+		return tagName
+	}
+	return tagName + "_" + profile
+}
+
+func ConvertProfile(profile string, source, dest interface{}) error {
+	return defaultMapper.ConvertProfile(profile, source, dest)
+}
+
+func MarshalProfile(profile string, v any) ([]byte, error) {
+	return defaultMapper.MarshalProfile(profile, v)
+}
+
+func UnmarshalProfile(profile string, data []byte, v interface{}) error {
+	return defaultMapper.UnmarshalProfile(profile, data, v)
+}
+
+// ConvertProfile is Convert using the paths from the named profile's tags
+func (m *Mapper) ConvertProfile(profile string, source, dest interface{}) error {
+	return m.withProfile(profile).Convert(source, dest)
+}
+
+// MarshalProfile is Marshal using the paths from the named profile's tags. Fields without a tag for the
+// profile are left where encoding/json puts them, the plain mapper tag isn't used as a fallback.
+func (m *Mapper) MarshalProfile(profile string, v any) ([]byte, error) {
+	return m.withProfile(profile).Marshal(v)
+}
+
+// UnmarshalProfile is Unmarshal using the paths from the named profile's tags
+func (m *Mapper) UnmarshalProfile(profile string, data []byte, v interface{}) error {
+	return m.withProfile(profile).Unmarshal(data, v)
+}
+
+// withProfile returns a copy of m that reads the named profile's tags, sharing m's converters and options
+func (m *Mapper) withProfile(profile string) *Mapper {
+	profiled := *m
+	profiled.tags.Mapper = profileTagName(m.tags.Mapper, profile)
+	return &profiled
+}
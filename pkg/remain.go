@@ -0,0 +1,88 @@
+package pkg
+
+import (
+	"encoding/json"
+	"github.com/joomcode/errorx"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"reflect"
+)
+
+// remain marks the field that collects the keys no other field reads
+const remain = "remain"
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// checkRemainType makes sure the remain field can hold an object of arbitrary keys
+func checkRemainType(tagData tagInfo) error {
+	typ := tagData.Field.Type
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == rawMessageType || typ.Kind() == reflect.Map && typ.Key().Kind() == reflect.String {
+		return nil
+	}
+	return errorx.IllegalArgument.New("remain field %s must be a map with string keys or a json.RawMessage, not %s", tagData.Field.Name, tagData.Field.Type)
+}
+
+// fillRemain replaces whatever data has at the remain field's json key with the keys of source that no other field reads
+func fillRemain(source, data []byte, plan *typePlan, consumed [][]string) ([]byte, error) {
+	if err := checkRemainType(*plan.Remain); err != nil {
+		return nil, err
+	}
+	jsonPath := escapePathKey(plan.Remain.JsonFieldName)
+	data, err := sjson.DeleteBytes(data, jsonPath)
+	if err != nil {
+		return nil, err
+	}
+	unread := unreadKeys(gjson.ParseBytes(source), plan, consumed)
+	if len(unread) == 0 {
+		return data, nil
+	}
+	remaining := []byte("{}")
+	for _, key := range unread {
+		remaining, err = sjson.SetRawBytes(remaining, key.JSONPath, []byte(gjson.GetBytes(source, key.JSONPath).Raw))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sjson.SetRawBytes(data, jsonPath, remaining)
+}
+
+// mergeRemain moves the keys of the remain field back into data, leaving the ones other fields already wrote alone
+func mergeRemain(data []byte, plan *typePlan) ([]byte, error) {
+	if err := checkRemainType(*plan.Remain); err != nil {
+		return nil, err
+	}
+	jsonPath := escapePathKey(plan.Remain.DocumentKey)
+	remaining := gjson.GetBytes(data, jsonPath)
+	if !remaining.Exists() {
+		return data, nil
+	}
+	data, err := sjson.DeleteBytes(data, jsonPath)
+	if err != nil || !remaining.IsObject() {
+		return data, err
+	}
+	remaining.ForEach(func(key, value gjson.Result) bool {
+		data, err = mergeMissing(data, escapePathKey(key.String()), value)
+		return err == nil
+	})
+	return data, err
+}
+
+// mergeMissing writes value at path, unless data already has something there. Objects on both sides are merged key by key.
+func mergeMissing(data []byte, path string, value gjson.Result) ([]byte, error) {
+	existing := gjson.GetBytes(data, path)
+	if !existing.Exists() {
+		return sjson.SetRawBytes(data, path, []byte(value.Raw))
+	}
+	if !existing.IsObject() || !value.IsObject() {
+		return data, nil
+	}
+	var err error
+	value.ForEach(func(key, child gjson.Result) bool {
+		data, err = mergeMissing(data, path+"."+escapePathKey(key.String()), child)
+		return err == nil
+	})
+	return data, err
+}
@@ -0,0 +1,171 @@
+package pkg
+
+import (
+	"errors"
+	"github.com/tidwall/gjson"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const strict = "strict"
+
+// WithStrictCoercion makes every coerced field behave as if it had the strict tag option
+func WithStrictCoercion() Option {
+	return func(m *Mapper) {
+		m.strictCoercion = true
+	}
+}
+
+// strictCoerce converts result to a bool, integer or float of type typ, returning a CoercionFailed error
+// instead of silently losing data. ok is false for the kinds strict mode doesn't apply to.
+func strictCoerce(result gjson.Result, typ reflect.Type) (value any, ok bool, err error) {
+	target := reflect.New(typ).Elem()
+	switch typ.Kind() {
+	case reflect.Bool:
+		ok = true
+		var parsed bool
+		parsed, err = strictBool(result)
+		target.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		ok = true
+		var parsed int64
+		parsed, err = strictInt(result)
+		if err == nil && target.OverflowInt(parsed) {
+			err = CoercionFailed.New("%s overflows %s", result.Raw, typ)
+		}
+		target.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		ok = true
+		var parsed uint64
+		parsed, err = strictUint(result)
+		if err == nil && target.OverflowUint(parsed) {
+			err = CoercionFailed.New("%s overflows %s", result.Raw, typ)
+		}
+		target.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		ok = true
+		var parsed float64
+		parsed, err = strictFloat(result)
+		if err == nil && target.OverflowFloat(parsed) {
+			err = CoercionFailed.New("%s overflows %s", result.Raw, typ)
+		}
+		target.SetFloat(parsed)
+	default:
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, true, err
+	}
+	return target.Interface(), true, nil
+}
+
+func strictBool(result gjson.Result) (bool, error) {
+	switch result.Type {
+	case gjson.True:
+		return true, nil
+	case gjson.False:
+		return false, nil
+	case gjson.Number:
+		switch result.Raw {
+		case "0":
+			return false, nil
+		case "1":
+			return true, nil
+		}
+	case gjson.String:
+		parsed, err := strconv.ParseBool(result.Str)
+		if err == nil {
+			return parsed, nil
+		}
+	}
+	return false, CoercionFailed.New("cannot coerce %s to bool", result.Raw)
+}
+
+// numberText returns the text of a json number or of a string holding one
+func numberText(result gjson.Result) (string, bool) {
+	switch result.Type {
+	case gjson.Number:
+		return result.Raw, true
+	case gjson.String:
+		return strings.TrimSpace(result.Str), true
+	}
+	return "", false
+}
+
+func strictInt(result gjson.Result) (int64, error) {
+	text, ok := numberText(result)
+	if !ok {
+		return 0, CoercionFailed.New("cannot coerce %s to an integer", result.Raw)
+	}
+	parsed, err := strconv.ParseInt(text, 10, 64)
+	if err == nil {
+		return parsed, nil
+	}
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, CoercionFailed.New("%s overflows int64", result.Raw)
+	}
+	// 1e3 and 3.0 are still whole numbers
+	float, err := strictFloat(result)
+	if err != nil {
+		return 0, err
+	}
+	if float != math.Trunc(float) {
+		return 0, CoercionFailed.New("%s would be truncated to an integer", result.Raw)
+	}
+	if float < math.MinInt64 || float >= math.MaxInt64 {
+		return 0, CoercionFailed.New("%s overflows int64", result.Raw)
+	}
+	return int64(float), nil
+}
+
+func strictUint(result gjson.Result) (uint64, error) {
+	text, ok := numberText(result)
+	if !ok {
+		return 0, CoercionFailed.New("cannot coerce %s to an unsigned integer", result.Raw)
+	}
+	if strings.HasPrefix(text, "-") {
+		parsed, err := strictInt(result)
+		if err != nil {
+			return 0, err
+		}
+		if parsed != 0 {
+			return 0, CoercionFailed.New("cannot coerce negative %s to an unsigned integer", result.Raw)
+		}
+		return 0, nil
+	}
+	parsed, err := strconv.ParseUint(text, 10, 64)
+	if err == nil {
+		return parsed, nil
+	}
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, CoercionFailed.New("%s overflows uint64", result.Raw)
+	}
+	float, err := strictFloat(result)
+	if err != nil {
+		return 0, err
+	}
+	if float != math.Trunc(float) {
+		return 0, CoercionFailed.New("%s would be truncated to an integer", result.Raw)
+	}
+	if float >= math.MaxUint64 {
+		return 0, CoercionFailed.New("%s overflows uint64", result.Raw)
+	}
+	return uint64(float), nil
+}
+
+func strictFloat(result gjson.Result) (float64, error) {
+	text, ok := numberText(result)
+	if !ok {
+		return 0, CoercionFailed.New("cannot coerce %s to a float", result.Raw)
+	}
+	parsed, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, CoercionFailed.New("%s overflows float64", result.Raw)
+		}
+		return 0, CoercionFailed.New("cannot coerce %s to a float", result.Raw)
+	}
+	return parsed, nil
+}
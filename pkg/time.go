@@ -0,0 +1,154 @@
+package pkg
+
+import (
+	"github.com/joomcode/errorx"
+	"github.com/tidwall/gjson"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+const (
+	layoutUnix      = "unix"
+	layoutUnixMilli = "unixmilli"
+	layoutUnixMicro = "unixmicro"
+	layoutUnixNano  = "unixnano"
+	layoutSeconds   = "seconds"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+
+	// namedLayouts lets tags refer to the layouts of the time package by name, which also avoids the commas in some of them
+	namedLayouts = map[string]string{
+		"ANSIC":       time.ANSIC,
+		"UnixDate":    time.UnixDate,
+		"RubyDate":    time.RubyDate,
+		"RFC822":      time.RFC822,
+		"RFC822Z":     time.RFC822Z,
+		"RFC850":      time.RFC850,
+		"RFC1123":     time.RFC1123,
+		"RFC1123Z":    time.RFC1123Z,
+		"RFC3339":     time.RFC3339,
+		"RFC3339Nano": time.RFC3339Nano,
+		"Kitchen":     time.Kitchen,
+		"Stamp":       time.Stamp,
+		"StampMilli":  time.StampMilli,
+		"StampMicro":  time.StampMicro,
+		"StampNano":   time.StampNano,
+		"DateTime":    time.DateTime,
+		"DateOnly":    time.DateOnly,
+		"TimeOnly":    time.TimeOnly,
+	}
+
+	// epochUnits are the layouts that read and write a number of units since the unix epoch
+	epochUnits = map[string]time.Duration{
+		layoutUnix:      time.Second,
+		layoutUnixMilli: time.Millisecond,
+		layoutUnixMicro: time.Microsecond,
+		layoutUnixNano:  time.Nanosecond,
+	}
+)
+
+// coerceTime converts between the json at a mapped path and a time.Time field.
+// Values are read as epoch values when the layout names an epoch unit, and numbers are read as epoch seconds when
+// there is no layout. Anything else is parsed with the layout, RFC3339 by default. When marshalling the field is written back out in the same layout.
+func coerceTime(result gjson.Result, tagData tagInfo, dir direction) (any, error) {
+	if result.Type == gjson.Null {
+		return nil, nil
+	}
+	location := tagData.Location
+	if tagData.TimeZone != "" && location == nil {
+		return nil, errorx.IllegalArgument.New("unknown time zone %s", tagData.TimeZone)
+	}
+	layout := tagData.Layout
+	if named, ok := namedLayouts[layout]; ok {
+		layout = named
+	}
+	unit, isEpoch := epochUnits[layout]
+
+	if dir == marshalling {
+		// the field's own encoding is always RFC3339
+		t, err := time.Parse(time.RFC3339Nano, result.String())
+		if err != nil {
+			return nil, errorx.IllegalFormat.Wrap(err, "cannot coerce %s to time", result.Raw)
+		}
+		if location != nil {
+			t = t.In(location)
+		}
+		switch {
+		case isEpoch:
+			return t.UnixNano() / int64(unit), nil
+		case layout != "":
+			return t.Format(layout), nil
+		}
+		return t.Format(time.RFC3339Nano), nil
+	}
+
+	var t time.Time
+	// a layout other than an epoch unit parses every value, even ones made of digits like 20240115
+	if isEpoch || layout == "" && (result.Type == gjson.Number || isNumeric(result.String())) {
+		if !isEpoch {
+			unit = time.Second
+		}
+		t = epochToTime(result, unit)
+	} else {
+		if layout == "" {
+			layout = time.RFC3339Nano
+		}
+		parseLocation := location
+		if parseLocation == nil {
+			parseLocation = time.UTC
+		}
+		var err error
+		t, err = time.ParseInLocation(layout, result.String(), parseLocation)
+		if err != nil {
+			return nil, errorx.IllegalFormat.Wrap(err, "cannot coerce %s to time", result.Raw)
+		}
+	}
+	if location != nil {
+		t = t.In(location)
+	}
+	return t, nil
+}
+
+// coerceDuration converts between the json at a mapped path and a time.Duration field.
+// Strings are parsed with time.ParseDuration and numbers are read as seconds. When marshalling the field
+// is written as a duration string, or as a number of seconds with layout=seconds.
+func coerceDuration(result gjson.Result, tagData tagInfo, dir direction) (any, error) {
+	if result.Type == gjson.Null {
+		return nil, nil
+	}
+	if dir == marshalling {
+		// the field's own encoding is a number of nanoseconds
+		duration := time.Duration(result.Int())
+		if tagData.Layout == layoutSeconds {
+			return duration.Seconds(), nil
+		}
+		return duration.String(), nil
+	}
+	if result.Type == gjson.Number || isNumeric(result.String()) {
+		return time.Duration(result.Float() * float64(time.Second)), nil
+	}
+	duration, err := time.ParseDuration(result.String())
+	if err != nil {
+		return nil, errorx.IllegalFormat.Wrap(err, "cannot coerce %s to duration", result.Raw)
+	}
+	return duration, nil
+}
+
+func epochToTime(result gjson.Result, unit time.Duration) time.Time {
+	if unit == time.Second {
+		// seconds are the only unit where a fraction is common
+		seconds := result.Float()
+		whole := int64(seconds)
+		return time.Unix(whole, int64((seconds-float64(whole))*float64(time.Second))).UTC()
+	}
+	return time.Unix(0, result.Int()*int64(unit)).UTC()
+}
+
+func isNumeric(value string) bool {
+	_, err := strconv.ParseFloat(value, 64)
+	return err == nil
+}
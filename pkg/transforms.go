@@ -0,0 +1,277 @@
+package pkg
+
+import (
+	"encoding/json"
+	"github.com/tidwall/gjson"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const transformPrefix = "transform="
+
+// TransformFunc changes a mapped value. It receives the value's json and the argument given in the tag,
+// e.g. "," for split(,), and the returned value is json encoded in place of the original json.
+type TransformFunc func(value gjson.Result, arg string) (any, error)
+
+// transform is a registered transform, Inverse is nil for transforms that can't be undone
+type transform struct {
+	Apply   TransformFunc
+	Inverse TransformFunc
+}
+
+// transformStep is a single transform named in a mapper tag
+type transformStep struct {
+	Name string
+	Arg  string
+}
+
+type transformRegistry struct {
+	lock       sync.RWMutex
+	transforms map[string]transform
+}
+
+// globalTransforms are consulted by every Mapper after its own transforms, they start out with the built in ones
+var globalTransforms = newTransformRegistry(map[string]transform{
+	"trim":     {Apply: stringTransform(strings.TrimSpace)},
+	"lower":    {Apply: stringTransform(strings.ToLower)},
+	"upper":    {Apply: stringTransform(strings.ToUpper)},
+	"split":    {Apply: splitTransform, Inverse: joinTransform},
+	"join":     {Apply: joinTransform, Inverse: splitTransform},
+	"round":    {Apply: roundTransform},
+	"multiply": {Apply: multiplyTransform, Inverse: divideTransform},
+	"divide":   {Apply: divideTransform, Inverse: multiplyTransform},
+	"first":    {Apply: firstTransform, Inverse: wrapTransform},
+	"compact":  {Apply: compactTransform},
+})
+
+func newTransformRegistry(transforms map[string]transform) *transformRegistry {
+	return &transformRegistry{transforms: transforms}
+}
+
+func (r *transformRegistry) register(name string, t transform) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.transforms[name] = t
+}
+
+func (r *transformRegistry) lookup(name string) (transform, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	t, ok := r.transforms[name]
+	return t, ok
+}
+
+// RegisterTransform makes a named transform available to the transform tag option of every Mapper.
+// fn runs on Unmarshal, and inverse, if it isn't nil, undoes it on Marshal. Registering a built in name replaces it.
+func RegisterTransform(name string, fn, inverse TransformFunc) {
+	globalTransforms.register(name, transform{Apply: fn, Inverse: inverse})
+}
+
+// RegisterTransform registers a transform that is only used by m, it takes precedence over a global transform with the same name
+func (m *Mapper) RegisterTransform(name string, fn, inverse TransformFunc) {
+	m.transforms.register(name, transform{Apply: fn, Inverse: inverse})
+}
+
+// WithTransform registers a transform that is only used by the new Mapper
+func WithTransform(name string, fn, inverse TransformFunc) Option {
+	return func(m *Mapper) {
+		m.RegisterTransform(name, fn, inverse)
+	}
+}
+
+// getTransform finds the named transform, preferring the ones registered on m
+func (m *Mapper) getTransform(name string) (transform, bool) {
+	if t, ok := m.transforms.lookup(name); ok {
+		return t, true
+	}
+	return globalTransforms.lookup(name)
+}
+
+// parseTransforms reads the steps of a transform tag option, e.g. trim|lower|split(,)
+func parseTransforms(value string) []transformStep {
+	steps := []transformStep{}
+	for _, part := range splitOutside(value, '|') {
+		name, arg, found := strings.Cut(part, "(")
+		if found && strings.HasSuffix(arg, ")") {
+			arg = strings.TrimSuffix(arg, ")")
+		} else {
+			name, arg = part, ""
+		}
+		steps = append(steps, transformStep{Name: name, Arg: arg})
+	}
+	return steps
+}
+
+// applyTransforms runs the field's transforms over result. On unmarshal they run in tag order, on marshal
+// their inverses run in reverse order and transforms without an inverse are skipped. Null is left alone.
+func (m *Mapper) applyTransforms(result gjson.Result, tagData tagInfo, dir direction) (gjson.Result, error) {
+	steps := tagData.Transforms
+	for i := range steps {
+		step := steps[i]
+		if dir == marshalling {
+			step = steps[len(steps)-1-i]
+		}
+		t, ok := m.getTransform(step.Name)
+		if !ok {
+			return result, TransformFailed.New("unknown transform %s", step.Name)
+		}
+		fn := t.Apply
+		if dir == marshalling {
+			fn = t.Inverse
+		}
+		if fn == nil || !result.Exists() || result.Type == gjson.Null {
+			continue
+		}
+		value, err := encodeCoerced(fn(result, step.Arg))
+		if err != nil {
+			return result, TransformFailed.Wrap(err, "transform %s failed", step.Name)
+		}
+		result = gjson.Parse(value)
+	}
+	return result, nil
+}
+
+// stringTransform applies fn to strings and leaves every other value unchanged
+func stringTransform(fn func(string) string) TransformFunc {
+	return func(value gjson.Result, _ string) (any, error) {
+		if value.Type != gjson.String {
+			return json.RawMessage(value.Raw), nil
+		}
+		return fn(value.Str), nil
+	}
+}
+
+// splitTransform splits a string into an array of strings, at arg or at commas when there is no arg
+func splitTransform(value gjson.Result, arg string) (any, error) {
+	if value.Type != gjson.String {
+		return nil, TransformFailed.New("split needs a string, got %s", value.Raw)
+	}
+	if value.Str == "" {
+		return []string{}, nil
+	}
+	return strings.Split(value.Str, separator(arg)), nil
+}
+
+// joinTransform joins the elements of an array into a string, with arg or a comma between them
+func joinTransform(value gjson.Result, arg string) (any, error) {
+	if !value.IsArray() {
+		return nil, TransformFailed.New("join needs an array, got %s", value.Raw)
+	}
+	parts := []string{}
+	for _, element := range value.Array() {
+		parts = append(parts, element.String())
+	}
+	return strings.Join(parts, separator(arg)), nil
+}
+
+func separator(arg string) string {
+	if arg == "" {
+		return ","
+	}
+	return arg
+}
+
+// roundTransform rounds a number to arg decimal places, or to a whole number when there is no arg
+func roundTransform(value gjson.Result, arg string) (any, error) {
+	places, err := numberArg(arg, 0)
+	if err != nil {
+		return nil, err
+	}
+	if value.Type != gjson.Number {
+		return nil, TransformFailed.New("round needs a number, got %s", value.Raw)
+	}
+	scale := math.Pow(10, math.Trunc(places))
+	return math.Round(value.Num*scale) / scale, nil
+}
+
+// multiplyTransform multiplies a number by arg, e.g. multiply(100) turns dollars into cents.
+// The decimal text is multiplied exactly, so 19.99 becomes 1999 rather than 1998.9999999999998.
+func multiplyTransform(value gjson.Result, arg string) (any, error) {
+	number, factor, err := rationalOperands("multiply", value, arg)
+	if err != nil {
+		return nil, err
+	}
+	result, _ := number.Mul(number, factor).Float64()
+	return result, nil
+}
+
+// divideTransform divides a number by arg
+func divideTransform(value gjson.Result, arg string) (any, error) {
+	number, divisor, err := rationalOperands("divide", value, arg)
+	if err != nil {
+		return nil, err
+	}
+	if divisor.Sign() == 0 {
+		return nil, TransformFailed.New("can't divide by zero")
+	}
+	result, _ := number.Quo(number, divisor).Float64()
+	return result, nil
+}
+
+// rationalOperands reads a json number and a transform argument, which defaults to 1, as exact fractions
+func rationalOperands(name string, value gjson.Result, arg string) (*big.Rat, *big.Rat, error) {
+	if value.Type != gjson.Number {
+		return nil, nil, TransformFailed.New("%s needs a number, got %s", name, value.Raw)
+	}
+	number, ok := new(big.Rat).SetString(value.Raw)
+	if !ok {
+		return nil, nil, TransformFailed.New("%s is not a number", value.Raw)
+	}
+	if arg == "" {
+		arg = "1"
+	}
+	operand, ok := new(big.Rat).SetString(arg)
+	if !ok {
+		return nil, nil, TransformFailed.New("%s is not a number", arg)
+	}
+	return number, operand, nil
+}
+
+func numberArg(arg string, fallback float64) (float64, error) {
+	if arg == "" {
+		return fallback, nil
+	}
+	number, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return 0, TransformFailed.New("%s is not a number", arg)
+	}
+	return number, nil
+}
+
+// firstTransform takes the first element of an array, or null for an empty one. Other values are left unchanged.
+func firstTransform(value gjson.Result, _ string) (any, error) {
+	if !value.IsArray() {
+		return json.RawMessage(value.Raw), nil
+	}
+	elements := value.Array()
+	if len(elements) == 0 {
+		return nil, nil
+	}
+	return json.RawMessage(elements[0].Raw), nil
+}
+
+// wrapTransform puts a value in an array of its own, it undoes first
+func wrapTransform(value gjson.Result, _ string) (any, error) {
+	if value.IsArray() {
+		return json.RawMessage(value.Raw), nil
+	}
+	return []json.RawMessage{json.RawMessage(value.Raw)}, nil
+}
+
+// compactTransform drops the null and empty string elements of an array
+func compactTransform(value gjson.Result, _ string) (any, error) {
+	if !value.IsArray() {
+		return json.RawMessage(value.Raw), nil
+	}
+	elements := []json.RawMessage{}
+	for _, element := range value.Array() {
+		if element.Type == gjson.Null || element.Type == gjson.String && element.Str == "" {
+			continue
+		}
+		elements = append(elements, json.RawMessage(element.Raw))
+	}
+	return elements, nil
+}
@@ -0,0 +1,248 @@
+package pkg
+
+import (
+	"errors"
+	"github.com/joomcode/errorx"
+	"github.com/tidwall/gjson"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// UnknownFieldPolicy decides what Unmarshal does with keys in the data that no field reads
+type UnknownFieldPolicy int
+
+const (
+	// IgnoreUnknownFields leaves unknown keys alone, the way encoding/json does
+	IgnoreUnknownFields UnknownFieldPolicy = iota
+	// WarnUnknownFields calls the warning function with the path of every unknown key, and otherwise ignores them
+	WarnUnknownFields
+	// RejectUnknownFields fails with an UnknownField error for every unknown key
+	RejectUnknownFields
+)
+
+// WithUnknownFields sets what Unmarshal and Convert do with keys that no json key or mapped path reads.
+// warn is only used by WarnUnknownFields, and receives the full path of each unknown key.
+func WithUnknownFields(policy UnknownFieldPolicy, warn func(path string)) Option {
+	return func(m *Mapper) {
+		m.unknownFields = policy
+		m.warnUnknown = warn
+	}
+}
+
+// unknownFieldError reports a key no field reads, at path relative to the value being unmarshaled
+func unknownFieldError(path []string) *MappingError {
+	jsonPath := joinPath(path)
+	return &MappingError{JSONPath: jsonPath, Index: -1, Err: UnknownField.New("unknown field %s", jsonPath)}
+}
+
+// warnUnknownFields hands the unknown fields in err to the warning function, and returns the rest of err
+func (m *Mapper) warnUnknownFields(err error) error {
+	var many MappingErrors
+	if !errors.As(err, &many) {
+		if !errorx.IsOfType(err, UnknownField) {
+			return err
+		}
+		many = MappingErrors{asMappingError(err)}
+	}
+	rest := MappingErrors{}
+	for _, mappingErr := range many {
+		if !errorx.IsOfType(mappingErr, UnknownField) {
+			rest = append(rest, mappingErr)
+		} else if m.warnUnknown != nil {
+			m.warnUnknown(mappingErr.JSONPath)
+		}
+	}
+	return rest.err()
+}
+
+// consumedPaths returns the keys a mapped path read result from, each split into its path components.
+// When gjson can't tell, e.g. after a modifier, everything under the plain keys the path starts with counts as read.
+func consumedPaths(data []byte, result gjson.Result, path string) [][]string {
+	document := string(data)
+	paths := result.Paths(document)
+	if len(paths) == 0 {
+		if concrete := result.Path(document); concrete != "" && gjson.Get(document, concrete).Raw == result.Raw {
+			paths = []string{concrete}
+		}
+	}
+	consumed := [][]string{}
+	for _, concrete := range paths {
+		consumed = append(consumed, splitPath(concrete))
+	}
+	if len(consumed) > 0 {
+		return consumed
+	}
+	plain := []string{}
+	for _, component := range splitPath(path) {
+		if component == "" || component == "#" || component == appendIndex || strings.ContainsAny(component[:1], "#@{[!") || hasWildcard(component) {
+			break
+		}
+		plain = append(plain, component)
+	}
+	if len(plain) == 0 {
+		// nothing is known about what was read, so nothing is reported either
+		return [][]string{{}}
+	}
+	return [][]string{plain}
+}
+
+// unknownInStruct returns an UnknownField error for every key of data that isn't read by a mapped path in consumed
+// or by a field of plan. Nested fields check their own keys when they are unmarshaled. Keys a remain field
+// collects aren't unknown, only the ones inside other fields are.
+func (m *Mapper) unknownInStruct(data gjson.Result, plan *typePlan, consumed [][]string) MappingErrors {
+	unknown := MappingErrors{}
+	if plan.Remain == nil {
+		unknown = unreadKeys(data, plan, consumed)
+	}
+	if !data.IsObject() {
+		return unknown
+	}
+	data.ForEach(func(key, value gjson.Result) bool {
+		field, ok := matchField(plan, key.String())
+		if ok && !isNestedField(plan, field) {
+			unknown = append(unknown, m.unknownInType(value, field.Field.Type, []string{key.String()})...)
+		}
+		return true
+	})
+	return unknown
+}
+
+// unreadKeys returns an UnknownField error for every key of data that no field of plan, and no mapped path in consumed, reads
+func unreadKeys(data gjson.Result, plan *typePlan, consumed [][]string) MappingErrors {
+	unread := MappingErrors{}
+	if !data.IsObject() {
+		return unread
+	}
+	data.ForEach(func(key, value gjson.Result) bool {
+		if _, ok := matchField(plan, key.String()); !ok {
+			unread = append(unread, unknownInConsumed(value, []string{key.String()}, consumed)...)
+		}
+		return true
+	})
+	return unread
+}
+
+// unknownInConsumed reports the keys under path that no path in consumed reads
+func unknownInConsumed(value gjson.Result, path []string, consumed [][]string) MappingErrors {
+	read, parent := false, false
+	for _, consumedPath := range consumed {
+		if hasPathPrefix(path, consumedPath) {
+			read = true
+			break
+		}
+		if hasPathPrefix(consumedPath, path) {
+			parent = true
+		}
+	}
+	switch {
+	case read:
+		return nil
+	case !parent || !value.IsObject() && !value.IsArray():
+		return MappingErrors{unknownFieldError(path)}
+	}
+	unknown := MappingErrors{}
+	index := 0
+	value.ForEach(func(key, child gjson.Result) bool {
+		childPath := appendPath(path, key, index)
+		unknown = append(unknown, unknownInConsumed(child, childPath, consumed)...)
+		index++
+		return true
+	})
+	return unknown
+}
+
+// unknownInType reports the keys of value that encoding/json ignores when decoding it into typ
+func (m *Mapper) unknownInType(value gjson.Result, typ reflect.Type, path []string) MappingErrors {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if reflect.PointerTo(typ).Implements(jsonUnmarshalerType) || getTypeHooks(typ).Unmarshaler {
+		return nil
+	}
+	unknown := MappingErrors{}
+	switch {
+	case typ.Kind() == reflect.Struct && value.IsObject():
+		plan := getTypePlan(typ, m.tags)
+		value.ForEach(func(key, child gjson.Result) bool {
+			childPath := append(append([]string{}, path...), key.String())
+			if field, ok := matchField(plan, key.String()); ok {
+				unknown = append(unknown, m.unknownInType(child, field.Field.Type, childPath)...)
+			} else {
+				unknown = append(unknown, unknownFieldError(childPath))
+			}
+			return true
+		})
+	case (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) && value.IsArray():
+		for i, element := range value.Array() {
+			childPath := append(append([]string{}, path...), strconv.Itoa(i))
+			unknown = append(unknown, m.unknownInType(element, typ.Elem(), childPath)...)
+		}
+	}
+	// maps, interfaces and plain values take whatever they are given
+	return unknown
+}
+
+// matchField finds the field encoding/json decodes key into, preferring an exact match the way encoding/json does.
+// The remain field doesn't read its own key, it collects it like any other unread key.
+func matchField(plan *typePlan, key string) (tagInfo, bool) {
+	for _, exact := range []bool{true, false} {
+		for _, field := range plan.Visible {
+			if plan.Remain != nil && reflect.DeepEqual(field.Field.Index, plan.Remain.Field.Index) {
+				continue
+			}
+			if exact && field.DocumentKey == key || !exact && strings.EqualFold(field.DocumentKey, key) {
+				return field, true
+			}
+		}
+	}
+	return tagInfo{}, false
+}
+
+func isNestedField(plan *typePlan, field tagInfo) bool {
+	for _, nested := range plan.Nested {
+		if reflect.DeepEqual(nested.Field.Index, field.Field.Index) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPathPrefix(path, prefix []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// appendPath adds the key of an object member, or the index of an array element, to path
+func appendPath(path []string, key gjson.Result, index int) []string {
+	component := key.String()
+	if !key.Exists() {
+		component = strconv.Itoa(index)
+	}
+	return append(append([]string{}, path...), component)
+}
+
+// splitPath splits a gjson path into its unescaped components
+func splitPath(path string) []string {
+	components := splitOutside(path, '.')
+	for i, component := range components {
+		components[i] = unescapePathKey(component)
+	}
+	return components
+}
+
+// joinPath joins unescaped components into a gjson path
+func joinPath(path []string) string {
+	escaped := make([]string, len(path))
+	for i, component := range path {
+		escaped[i] = escapePathKey(component)
+	}
+	return strings.Join(escaped, ".")
+}
@@ -0,0 +1,152 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func (s *MapperSuite) TestPrimitiveSlices() {
+	strings := []string{"i", "like", "turtles"}
+	bytes, err := pkg.Marshal(strings)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), `["i","like","turtles"]`, string(bytes))
+	stringsDest := []string{}
+	err = pkg.Unmarshal(bytes, &stringsDest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), strings, stringsDest)
+
+	ints := []int{1, 2, 3}
+	bytes, err = pkg.Marshal(&ints)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), `[1,2,3]`, string(bytes))
+	intsDest := []int{}
+	err = pkg.Unmarshal(bytes, &intsDest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), ints, intsDest)
+}
+
+func (s *MapperSuite) TestTopLevelMapOfStructs() {
+	nonMapped := map[string]nonMappedStruct{
+		"first":  getRandomNonMappedStruct(),
+		"second": getRandomNonMappedStruct(),
+	}
+	mapped := map[string]mappedStruct{}
+	err := pkg.Convert(nonMapped, &mapped)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), mapped, len(nonMapped))
+	for key, value := range nonMapped {
+		s.assertNonMappedStructMappedStructEquality(value, mapped[key])
+	}
+
+	roundTripped := map[string]nonMappedStruct{}
+	err = pkg.Convert(mapped, &roundTripped)
+	require.NoError(s.T(), err)
+	for key, value := range nonMapped {
+		s.assertNonMappedStructEquality(value, roundTripped[key])
+	}
+}
+
+func (s *MapperSuite) TestSliceOfSlicesOfStructs() {
+	nonMapped := [][]nonMappedStruct{
+		getRandomNonMappedStructs(gofakeit.Number(1, 3)),
+		getRandomNonMappedStructs(gofakeit.Number(1, 3)),
+	}
+	mapped := [][]*mappedStruct{}
+	err := pkg.Convert(nonMapped, &mapped)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), mapped, len(nonMapped))
+	for i := range nonMapped {
+		s.assertnonMappedSliceMappedPointerSliceEquality(nonMapped[i], mapped[i])
+	}
+}
+
+func (s *MapperSuite) TestMapOfSlicesOfStructs() {
+	nonMapped := map[string][]nonMappedStruct{
+		"first": getRandomNonMappedStructs(gofakeit.Number(1, 3)),
+		"empty": {},
+	}
+	mapped := map[string][]mappedStruct{}
+	err := pkg.Convert(nonMapped, &mapped)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), mapped, len(nonMapped))
+	s.assertnonMappedSliceMappedSliceEquality(nonMapped["first"], mapped["first"])
+	require.Empty(s.T(), mapped["empty"])
+}
+
+func (s *MapperSuite) TestMarshalNil() {
+	bytes, err := pkg.Marshal(nil)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "null", string(bytes))
+	var nilStruct *mappedStruct
+	bytes, err = pkg.Marshal(nilStruct)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "null", string(bytes))
+}
+
+func (s *MapperSuite) TestMarshalMapsLikeEncodingJSON() {
+	values := map[string]any{"b": 1, "a": []any{"x", nil}, "<html>": "&", "nested": map[string]any{"z": true, "y": 2.5}}
+	data, err := pkg.Marshal(values)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), string(must(json.Marshal(values))), string(data))
+
+	byNumber := map[int]string{10: "ten", -1: "minus one", 2: "two"}
+	data, err = pkg.Marshal(map[int]any{10: "ten", -1: "minus one", 2: "two"})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), string(must(json.Marshal(byNumber))), string(data))
+
+	// mapped values are written in place of the plain ones
+	mapped := getRandomMappedStruct()
+	data, err = pkg.Marshal(map[uint]any{7: mapped, 3: "plain"})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "plain", gjsonGet(data, "3"))
+	require.Equal(s.T(), mapped.SomeOtherString, gjsonGet(data, "7.a_string"))
+}
+
+func BenchmarkMarshalLargeMap(b *testing.B) {
+	holder := struct {
+		Values map[string]any `json:"values"`
+	}{Values: map[string]any{}}
+	for i := 0; i < 16000; i++ {
+		holder.Values["key"+strconv.Itoa(i)] = i
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := pkg.Marshal(holder); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (s *MapperSuite) TestIntegerKeyedMaps() {
+	nonMapped := map[int]nonMappedStruct{-3: getRandomNonMappedStruct(), 12: getRandomNonMappedStruct()}
+	mapped := map[int]mappedStruct{}
+	require.NoError(s.T(), pkg.Convert(nonMapped, &mapped))
+	require.Len(s.T(), mapped, 2)
+	for key, value := range nonMapped {
+		s.assertNonMappedStructMappedStructEquality(value, mapped[key])
+	}
+
+	byID := map[uint8]mappedStruct{}
+	require.NoError(s.T(), pkg.Unmarshal([]byte(`{"7": {"a_string": "seven"}}`), &byID))
+	require.Equal(s.T(), "seven", byID[7].SomeOtherString)
+	// like encoding/json, keys that don't fit are rejected
+	require.Error(s.T(), pkg.Unmarshal([]byte(`{"300": {}}`), &byID))
+	require.Error(s.T(), pkg.Unmarshal([]byte(`{"x": {}}`), &byID))
+}
+
+func (s *MapperSuite) TestNullLeavesArraysAlone() {
+	mapped := [2]mappedStruct{getRandomMappedStruct(), getRandomMappedStruct()}
+	kept := mapped
+	require.NoError(s.T(), pkg.Unmarshal([]byte(`null`), &mapped))
+	require.Equal(s.T(), kept, mapped)
+
+	holder := struct {
+		Pair [2]mappedStruct `json:"pair"`
+	}{Pair: kept}
+	require.NoError(s.T(), pkg.Unmarshal([]byte(`{"pair": null}`), &holder))
+	require.Equal(s.T(), kept, holder.Pair)
+}
@@ -0,0 +1,175 @@
+package test
+
+import (
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+	"time"
+)
+
+// requireConvertParity converts source into a fresh dest and checks the result, and the error, match the json round trip
+func (s *MapperSuite) requireConvertParity(mapper *pkg.Mapper, source any, newDest func() any) {
+	converted := newDest()
+	convertErr := mapper.Convert(source, converted)
+
+	roundTripped := newDest()
+	data, roundTripErr := mapper.Marshal(source)
+	if roundTripErr == nil {
+		roundTripErr = mapper.Unmarshal(data, roundTripped)
+	}
+
+	if roundTripErr != nil {
+		require.EqualError(s.T(), convertErr, roundTripErr.Error())
+	} else {
+		require.NoError(s.T(), convertErr)
+	}
+	require.Equal(s.T(), roundTripped, converted)
+}
+
+func (s *MapperSuite) TestConvertParityRandom() {
+	for i := 0; i < 50; i++ {
+		s.requireConvertParity(pkg.New(), getRandomNonMappedStruct(), func() any { return &mappedStruct{} })
+		s.requireConvertParity(pkg.New(), getRandomMappedStruct(), func() any { return &nonMappedStruct{} })
+		mapped := getRandomMappedStruct()
+		s.requireConvertParity(pkg.New(), &mapped, func() any { return &mappedStruct{} })
+	}
+}
+
+type convertSource struct {
+	ID       string   `json:"id" mapper:"ref.id"`
+	Name     string   `json:"name"`
+	Count    string   `json:"count,omitempty"`
+	Price    float64  `json:"price" mapper:"amounts.price"`
+	Tiny     float32  `json:"tiny"`
+	Nickname *string  `json:"nickname"`
+	Score    *float64 `json:"score"`
+	Active   bool     `json:"active" mapper:"flags.active,move"`
+}
+
+type convertDest struct {
+	Ref      string  `json:"ref" mapper:"ref.id"`
+	Name     string  `json:"name" mapper:",transform=trim|upper"`
+	Count    int     `json:"count" mapper:"count,coerce,default=7"`
+	Cents    int64   `json:"cents" mapper:"amounts.price,transform=multiply(100)"`
+	Tiny     float64 `json:"tiny"`
+	Nickname *string `json:"nickname" mapper:"nickname|name"`
+	Score    int     `json:"score" mapper:",coerce"`
+	Active   *bool   `json:"active" mapper:"flags.active"`
+	Missing  string  `json:"missing" mapper:"nowhere"`
+	Status   label   `json:"status" mapper:"name,coerce"`
+}
+
+type label string
+
+func (s *MapperSuite) TestConvertParity() {
+	nickname := "<Ann & co>"
+	score := 9.75
+	sources := []convertSource{
+		{},
+		{ID: "1", Name: "  ann ", Count: "12", Price: 19.99, Tiny: 0.1, Nickname: &nickname, Score: &score, Active: true},
+		{ID: "é ", Name: "x\"y\\z", Count: "nope", Price: 1e21, Tiny: 1e-7},
+		{Price: -0.000001, Tiny: float32(math.MaxFloat32), Score: &score},
+	}
+	for _, mapper := range []*pkg.Mapper{pkg.New(), pkg.New(pkg.WithMovedFields()), pkg.New(pkg.WithDefaultCoercion())} {
+		for _, source := range sources {
+			s.requireConvertParity(mapper, source, func() any { return &convertDest{} })
+			s.requireConvertParity(mapper, &source, func() any { return &convertSource{} })
+		}
+	}
+}
+
+func (s *MapperSuite) TestConvertParityErrors() {
+	type strictDest struct {
+		Count int `json:"count" mapper:"count,strict"`
+	}
+	type requiredDest struct {
+		ID string `json:"id" mapper:"ref.id,required"`
+	}
+	type plainDest struct {
+		Count int `json:"count"`
+	}
+	type nanSource struct {
+		Count string  `json:"count"`
+		Ratio float64 `json:"ratio"`
+	}
+	s.requireConvertParity(pkg.New(), convertSource{Count: "1.5"}, func() any { return &strictDest{} })
+	s.requireConvertParity(pkg.New(), convertSource{}, func() any { return &requiredDest{} })
+	s.requireConvertParity(pkg.New(pkg.WithErrorAggregation()), convertSource{}, func() any { return &requiredDest{} })
+	s.requireConvertParity(pkg.New(), convertSource{Count: "3"}, func() any { return &plainDest{} })
+	s.requireConvertParity(pkg.New(), nanSource{Count: "3", Ratio: math.NaN()}, func() any { return &plainDest{} })
+}
+
+func (s *MapperSuite) TestConvertParityEmbedded() {
+	type Base struct {
+		ID   int    `json:"id"`
+		Kind string `json:"kind" mapper:"meta.kind"`
+	}
+	type withPointer struct {
+		*Base
+		Name string `json:"name"`
+	}
+	type withValue struct {
+		Base
+		Name string `json:"name" mapper:"name,transform=upper"`
+	}
+	type flat struct {
+		ID   int    `json:"id"`
+		Kind string `json:"kind" mapper:"meta.kind"`
+		Name string `json:"name"`
+	}
+	s.requireConvertParity(pkg.New(), withPointer{Name: "a"}, func() any { return &flat{} })
+	s.requireConvertParity(pkg.New(), withPointer{Base: &Base{ID: 1, Kind: "k"}, Name: "a"}, func() any { return &flat{} })
+	s.requireConvertParity(pkg.New(), flat{ID: 2, Kind: "k", Name: "b"}, func() any { return &withValue{} })
+	s.requireConvertParity(pkg.New(), flat{ID: 2, Kind: "k", Name: "b"}, func() any { return &withPointer{} })
+}
+
+func (s *MapperSuite) TestConvertParityFallback() {
+	// times, nested structs and case insensitive keys go through the json round trip
+	type timed struct {
+		At   time.Time `json:"at"`
+		Name string    `json:"NAME"`
+	}
+	type nested struct {
+		Inner timed  `json:"inner" mapper:"wrapped"`
+		Name  string `json:"name"`
+	}
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	s.requireConvertParity(pkg.New(), timed{At: now, Name: "a"}, func() any { return &timed{} })
+	s.requireConvertParity(pkg.New(), timed{At: now, Name: "a"}, func() any { return &convertDest{} })
+	s.requireConvertParity(pkg.New(), nested{Inner: timed{At: now, Name: "b"}, Name: "a"}, func() any { return &nested{} })
+}
+
+func (s *MapperSuite) TestConvertKeepsUnsetFields() {
+	nickname := "kept"
+	dest := convertDest{Ref: "kept", Nickname: &nickname, Tiny: 1.5}
+	require.NoError(s.T(), pkg.Convert(struct {
+		Name string `json:"name"`
+	}{Name: "ann"}, &dest))
+	require.Equal(s.T(), "kept", dest.Ref)
+	require.Equal(s.T(), "ann", *dest.Nickname)
+	require.Equal(s.T(), 1.5, dest.Tiny)
+	require.Equal(s.T(), "ANN", dest.Name)
+}
+
+func (s *MapperSuite) TestConvertSkipsJSON() {
+	source := getRandomNonMappedStruct()
+	converted := testing.AllocsPerRun(100, func() {
+		_ = pkg.Convert(source, &mappedStruct{})
+	})
+	roundTripped := testing.AllocsPerRun(100, func() {
+		_ = pkg.Unmarshal(must(pkg.Marshal(source)), &mappedStruct{})
+	})
+	require.Less(s.T(), converted, roundTripped/2)
+}
+
+func BenchmarkConvert(b *testing.B) {
+	nonMapped := getRandomNonMappedStruct()
+
+	for n := 0; n < b.N; n++ {
+		var mapped mappedStruct
+		if err := pkg.Convert(nonMapped, &mapped); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package test
+
+import (
+	"fmt"
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type money struct {
+	Cents    int64  `json:"cents"`
+	Currency string `json:"currency"`
+}
+
+type status int
+
+const (
+	statusUnknown status = iota
+	statusActive
+	statusClosed
+)
+
+type geoPoint struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// "12.34 USD" <-> money{Cents: 1234, Currency: "USD"}
+func moneyFromString(result gjson.Result) (any, error) {
+	amount, currency, ok := strings.Cut(result.String(), " ")
+	if !ok {
+		return nil, fmt.Errorf("invalid money %q", result.String())
+	}
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return nil, err
+	}
+	return money{Cents: int64(value*100 + 0.5), Currency: currency}, nil
+}
+
+func moneyToString(result gjson.Result) (any, error) {
+	cents := result.Get("cents").Int()
+	return fmt.Sprintf("%d.%02d %s", cents/100, cents%100, result.Get("currency").String()), nil
+}
+
+func (s *MapperSuite) TestInstanceConverters() {
+	type external struct {
+		Price string `json:"price"`
+		State string `json:"state"`
+	}
+	type internal struct {
+		Price  money   `json:"price" mapper:"price,coerce"`
+		Status *status `json:"status" mapper:"state,coerce"`
+	}
+	mapper := pkg.New(
+		pkg.WithConverter(gjson.String, reflect.TypeOf(money{}), moneyFromString),
+		pkg.WithConverter(gjson.JSON, reflect.TypeOf(money{}), moneyToString),
+	)
+	mapper.RegisterConverter(gjson.String, reflect.TypeOf(statusUnknown), func(result gjson.Result) (any, error) {
+		switch result.String() {
+		case "active":
+			return statusActive, nil
+		case "closed":
+			return statusClosed, nil
+		}
+		return statusUnknown, nil
+	})
+
+	dest := internal{}
+	err := mapper.Convert(external{Price: "12.34 USD", State: "closed"}, &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), money{Cents: 1234, Currency: "USD"}, dest.Price)
+	require.NotNil(s.T(), dest.Status)
+	require.Equal(s.T(), statusClosed, *dest.Status)
+
+	bytes, err := mapper.Marshal(dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "12.34 USD", gjsonGet(bytes, "price"))
+
+	// converters registered on an instance don't leak into other mappers
+	err = pkg.Convert(external{Price: "12.34 USD"}, &internal{})
+	require.Error(s.T(), err)
+}
+
+func (s *MapperSuite) TestGlobalConverters() {
+	type external struct {
+		Location string `json:"location"`
+	}
+	type internal struct {
+		Location geoPoint `json:"location" mapper:"location,coerce"`
+	}
+	pkg.RegisterConverter(gjson.String, reflect.TypeOf(geoPoint{}), func(result gjson.Result) (any, error) {
+		lat, lng, _ := strings.Cut(result.String(), ",")
+		point := geoPoint{}
+		point.Lat, _ = strconv.ParseFloat(lat, 64)
+		point.Lng, _ = strconv.ParseFloat(lng, 64)
+		return point, nil
+	})
+	// the registry is shared by every test, leave it as it was found
+	s.T().Cleanup(func() {
+		pkg.UnregisterConverter(gjson.String, reflect.TypeOf(geoPoint{}))
+	})
+
+	dest := internal{}
+	err := pkg.Convert(external{Location: "52.52,13.405"}, &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), geoPoint{Lat: 52.52, Lng: 13.405}, dest.Location)
+
+	dest = internal{}
+	err = pkg.New().Convert(external{Location: "1.5,2.5"}, &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), geoPoint{Lat: 1.5, Lng: 2.5}, dest.Location)
+}
+
+func (s *MapperSuite) TestConverterErrors() {
+	type internal struct {
+		Price money `json:"price" mapper:"price,coerce"`
+	}
+	mapper := pkg.New(pkg.WithConverter(gjson.String, reflect.TypeOf(money{}), moneyFromString))
+	err := mapper.Unmarshal([]byte(`{"price":"twelve"}`), &internal{})
+	require.Error(s.T(), err)
+	require.Contains(s.T(), err.Error(), `invalid money "twelve"`)
+}
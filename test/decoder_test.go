@@ -0,0 +1,209 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"testing"
+)
+
+// arrayReader streams a json array of count copies of element without ever holding the array, and counts the bytes read
+type arrayReader struct {
+	element []byte
+	count   int
+	piece   int
+	pending []byte
+	read    int
+}
+
+func (r *arrayReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		switch {
+		case r.piece > r.count+1:
+			return 0, io.EOF
+		case r.piece == 0:
+			r.pending = []byte("[")
+		case r.piece == r.count+1:
+			r.pending = []byte("]")
+		case r.piece == 1:
+			r.pending = r.element
+		default:
+			r.pending = append([]byte(","), r.element...)
+		}
+		r.piece++
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	r.read += n
+	return n, nil
+}
+
+func (s *MapperSuite) TestDecodeSliceMatchesUnmarshal() {
+	data := must(pkg.Marshal(getRandomNonMappedStructs(20)))
+
+	var unmarshaled, decoded []mappedStruct
+	require.NoError(s.T(), pkg.Unmarshal(data, &unmarshaled))
+	require.NoError(s.T(), pkg.NewDecoder(bytes.NewReader(data)).Decode(&decoded))
+	require.Equal(s.T(), unmarshaled, decoded)
+
+	var pointers []*mappedStruct
+	require.NoError(s.T(), pkg.NewDecoder(bytes.NewReader(data)).Decode(&pointers))
+	require.Len(s.T(), pointers, 20)
+	require.Equal(s.T(), unmarshaled[19], *pointers[19])
+}
+
+func (s *MapperSuite) TestDecodeNullAndEmpty() {
+	lines := []erroringLine{{Sku: "kept"}}
+	require.NoError(s.T(), pkg.NewDecoder(strings.NewReader(`null`)).Decode(&lines))
+	require.Nil(s.T(), lines)
+
+	require.NoError(s.T(), pkg.NewDecoder(strings.NewReader(` [ ] `)).Decode(&lines))
+	require.NotNil(s.T(), lines)
+	require.Empty(s.T(), lines)
+
+	err := pkg.NewDecoder(strings.NewReader(`{"qty": 1}`)).Decode(&lines)
+	require.Error(s.T(), err)
+	require.ErrorIs(s.T(), pkg.NewDecoder(strings.NewReader(``)).Decode(&lines), io.EOF)
+}
+
+// csvList decodes itself from a comma separated string
+type csvList []string
+
+func (l *csvList) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	*l = strings.Split(text, ",")
+	return nil
+}
+
+func (s *MapperSuite) TestDecodeSlicesMappedWhole() {
+	var raw []byte
+	require.NoError(s.T(), pkg.NewDecoder(strings.NewReader(`"aGk="`)).Decode(&raw))
+	require.Equal(s.T(), []byte("hi"), raw)
+
+	var list csvList
+	require.NoError(s.T(), pkg.NewDecoder(strings.NewReader(`"a,b"`)).Decode(&list))
+	require.Equal(s.T(), csvList{"a", "b"}, list)
+
+	var unmarshaled csvList
+	require.NoError(s.T(), pkg.Unmarshal([]byte(`"a,b"`), &unmarshaled))
+	require.Equal(s.T(), unmarshaled, list)
+}
+
+func (s *MapperSuite) TestDecodeValuesOneByOne() {
+	decoder := pkg.NewDecoder(strings.NewReader("{\"product\":{\"sku\":\"a\"}}\n{\"product\":{\"sku\":\"b\"}}\n"))
+	skus := []string{}
+	for {
+		var line erroringLine
+		err := decoder.Decode(&line)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(s.T(), err)
+		skus = append(skus, line.Sku)
+	}
+	require.Equal(s.T(), []string{"a", "b"}, skus)
+}
+
+func (s *MapperSuite) TestDecodeNDJSON() {
+	input := "{\"product\":{\"sku\":\"a\"},\"qty\":1}\n{\"product\":{\"sku\":\"b\"},\"qty\":2}\n\n"
+
+	decoder := pkg.NewDecoder(strings.NewReader(input))
+	decoder.SetNDJSON(true)
+	var lines []erroringLine
+	require.NoError(s.T(), decoder.Decode(&lines))
+	require.Equal(s.T(), []erroringLine{{Sku: "a", Quantity: 1}, {Sku: "b", Quantity: 2}}, lines)
+
+	decoder = pkg.NewDecoder(strings.NewReader(input))
+	decoder.SetNDJSON(true)
+	quantities := []int{}
+	require.NoError(s.T(), pkg.DecodeEach(decoder, func(line erroringLine) error {
+		quantities = append(quantities, line.Quantity)
+		return nil
+	}))
+	require.Equal(s.T(), []int{1, 2}, quantities)
+}
+
+func (s *MapperSuite) TestDecodeEachStreams() {
+	reader := &arrayReader{element: []byte(`{"product":{"sku":"abc"},"qty":3}`), count: 100000}
+	count := 0
+	firstRead := 0
+	err := pkg.DecodeEach(pkg.NewDecoder(reader), func(line erroringLine) error {
+		if count == 0 {
+			firstRead = reader.read
+		}
+		require.Equal(s.T(), erroringLine{Sku: "abc", Quantity: 3}, line)
+		count++
+		return nil
+	})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 100000, count)
+	// the first element is mapped long before the array is read
+	require.Less(s.T(), firstRead, 64*1024)
+}
+
+func (s *MapperSuite) TestDecodeEachStopsOnCallbackError() {
+	stop := errors.New("stop")
+	count := 0
+	err := pkg.DecodeEach(pkg.NewDecoder(strings.NewReader(`[1, 2, 3]`)), func(n int) error {
+		count++
+		if n == 2 {
+			return stop
+		}
+		return nil
+	})
+	require.ErrorIs(s.T(), err, stop)
+	require.Equal(s.T(), 2, count)
+}
+
+func (s *MapperSuite) TestDecodeErrors() {
+	data := `[{"qty": 1}, {"qty": "one"}, {"qty": "two"}, {"qty": 4}]`
+	unmarshalErr := pkg.Unmarshal([]byte(data), &[]erroringLine{})
+	decodeErr := pkg.NewDecoder(strings.NewReader(data)).Decode(&[]erroringLine{})
+	require.Error(s.T(), decodeErr)
+	require.EqualError(s.T(), decodeErr, unmarshalErr.Error())
+
+	// collected errors skip the elements that failed and name every one of them
+	mapper := pkg.New(pkg.WithErrorAggregation())
+	quantities := []int{}
+	err := pkg.DecodeEach(mapper.NewDecoder(strings.NewReader(data)), func(line erroringLine) error {
+		quantities = append(quantities, line.Quantity)
+		return nil
+	})
+	var many pkg.MappingErrors
+	require.True(s.T(), errors.As(err, &many))
+	require.Len(s.T(), many, 2)
+	require.Equal(s.T(), 1, many[0].Index)
+	require.Equal(s.T(), 2, many[1].Index)
+	require.Equal(s.T(), []int{1, 4}, quantities)
+}
+
+func (s *MapperSuite) TestDecodeWarnsUnknownFields() {
+	warned := []string{}
+	mapper := pkg.New(pkg.WithUnknownFields(pkg.WarnUnknownFields, func(path string) {
+		warned = append(warned, path)
+	}))
+	var lines []erroringLine
+	require.NoError(s.T(), mapper.NewDecoder(strings.NewReader(`[{"qty": 1}, {"qty": 2, "extra": true}]`)).Decode(&lines))
+	require.Equal(s.T(), []string{"1.extra"}, warned)
+	require.Len(s.T(), lines, 2)
+}
+
+func BenchmarkDecodeEach(b *testing.B) {
+	element := must(pkg.Marshal(getRandomNonMappedStruct()))
+	for n := 0; n < b.N; n++ {
+		reader := &arrayReader{element: element, count: 100}
+		err := pkg.DecodeEach(pkg.NewDecoder(reader), func(mapped mappedStruct) error {
+			return nil
+		})
+		if err != nil {
+			panic(err)
+		}
+	}
+}
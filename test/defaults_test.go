@@ -0,0 +1,69 @@
+package test
+
+import (
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/stretchr/testify/require"
+	"time"
+)
+
+type configWithDefaults struct {
+	Retries  int           `json:"retries" mapper:"config.retries,coerce,default=3"`
+	Verbose  bool          `json:"verbose" mapper:"config.verbose,default=true"`
+	Name     string        `json:"name" mapper:"config.name,default=unnamed"`
+	Ratio    *float64      `json:"ratio" mapper:"config.ratio,default=0.5"`
+	Timeout  time.Duration `json:"timeout" mapper:"config.timeout,coerce,default=1m30s"`
+	Starts   time.Time     `json:"starts" mapper:"config.starts,coerce,layout=DateOnly,default=2023-01-01"`
+	Required string        `json:"required" mapper:"config.required"`
+}
+
+func (s *MapperSuite) TestDefaultsForMissingPaths() {
+	dest := configWithDefaults{}
+	err := pkg.Unmarshal([]byte(`{"config":{"required":"here"}}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 3, dest.Retries)
+	require.True(s.T(), dest.Verbose)
+	require.Equal(s.T(), "unnamed", dest.Name)
+	require.NotNil(s.T(), dest.Ratio)
+	require.Equal(s.T(), 0.5, *dest.Ratio)
+	require.Equal(s.T(), 90*time.Second, dest.Timeout)
+	require.True(s.T(), time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).Equal(dest.Starts))
+	require.Equal(s.T(), "here", dest.Required)
+}
+
+func (s *MapperSuite) TestDefaultsForNullValues() {
+	dest := configWithDefaults{}
+	err := pkg.Unmarshal([]byte(`{"config":{"retries":null,"name":null}}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 3, dest.Retries)
+	require.Equal(s.T(), "unnamed", dest.Name)
+}
+
+func (s *MapperSuite) TestDefaultsDontOverridePresentValues() {
+	dest := configWithDefaults{}
+	err := pkg.Unmarshal([]byte(`{"config":{"retries":"7","verbose":false,"name":"named","ratio":0.25}}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 7, dest.Retries)
+	require.False(s.T(), dest.Verbose)
+	require.Equal(s.T(), "named", dest.Name)
+	require.Equal(s.T(), 0.25, *dest.Ratio)
+}
+
+func (s *MapperSuite) TestMissingPathWithoutDefault() {
+	dest := configWithDefaults{Required: "untouched"}
+	err := pkg.Unmarshal([]byte(`{}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "untouched", dest.Required)
+}
+
+func (s *MapperSuite) TestDefaultWithConvert() {
+	type source struct {
+		Other string `json:"other"`
+	}
+	type dest struct {
+		Retries int `json:"retries" mapper:"retries,default=5"`
+	}
+	theDest := dest{}
+	err := pkg.Convert(source{Other: "value"}, &theDest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 5, theDest.Retries)
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"errors"
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/stretchr/testify/require"
+)
+
+type asymmetricAccount struct {
+	ID       string `json:"id" mapper:"in=data.id,out=account_id"`
+	Name     string `json:"name" mapper:"in=data.attributes.name|data.name,out=display_name"`
+	Created  string `json:"created" mapper:"data.meta.created,readonly"`
+	Password string `json:"password" mapper:"credentials.password,writeonly"`
+	Limit    int    `json:"limit" mapper:"data.limit,out=limits.max,strict"`
+}
+
+func (s *MapperSuite) TestDirectionalPathsUnmarshal() {
+	dest := asymmetricAccount{}
+	err := pkg.Unmarshal([]byte(`{"data":{"id":"42","name":"Acme","meta":{"created":"2023-01-01"},"limit":"10"},
+		"account_id":"ignored","credentials":{"password":"hunter2"}}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "42", dest.ID)
+	require.Equal(s.T(), "Acme", dest.Name)
+	require.Equal(s.T(), "2023-01-01", dest.Created)
+	require.Equal(s.T(), 10, dest.Limit)
+	// write only fields are never read from their mapped path
+	require.Empty(s.T(), dest.Password)
+}
+
+func (s *MapperSuite) TestDirectionalPathsMarshal() {
+	data, err := pkg.Marshal(asymmetricAccount{ID: "42", Name: "Acme", Created: "2023-01-01", Password: "hunter2", Limit: 10})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "42", gjsonGet(data, "account_id"))
+	require.Equal(s.T(), "Acme", gjsonGet(data, "display_name"))
+	require.Equal(s.T(), "hunter2", gjsonGet(data, "credentials.password"))
+	require.Equal(s.T(), "10", gjsonGet(data, "limits.max"))
+	// read only fields, and the in paths, aren't written
+	require.False(s.T(), gjsonExists(data, "data"))
+}
+
+func (s *MapperSuite) TestDirectionalPathsConvert() {
+	type request struct {
+		AccountID string `json:"account_id"`
+		Password  string `json:"password"`
+	}
+	type source struct {
+		ID       string `json:"id" mapper:"out=account_id"`
+		Password string `json:"secret" mapper:"out=password,writeonly"`
+		Created  string `json:"created" mapper:"readonly"`
+	}
+	dest := request{}
+	err := pkg.Convert(source{ID: "42", Password: "hunter2", Created: "today"}, &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "42", dest.AccountID)
+	require.Equal(s.T(), "hunter2", dest.Password)
+}
+
+func (s *MapperSuite) TestWriteOnlyIgnoresRequired() {
+	type login struct {
+		User     string `json:"user" mapper:"auth.user,required"`
+		Password string `json:"password" mapper:"auth.password,required,writeonly"`
+	}
+	dest := login{}
+	err := pkg.Unmarshal([]byte(`{"auth":{"user":"ann"}}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "ann", dest.User)
+}
+
+func (s *MapperSuite) TestDirectionalPathsErrors() {
+	err := pkg.Unmarshal([]byte(`{"data":{"limit":"lots"}}`), &asymmetricAccount{})
+	require.Error(s.T(), err)
+	var mappingErr *pkg.MappingError
+	require.True(s.T(), errors.As(err, &mappingErr))
+	require.Equal(s.T(), "Limit", mappingErr.Field)
+	require.Equal(s.T(), "data.limit", mappingErr.MapperPath)
+	require.Equal(s.T(), "data.limit", mappingErr.JSONPath)
+}
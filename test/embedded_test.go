@@ -0,0 +1,113 @@
+package test
+
+import (
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/stretchr/testify/require"
+)
+
+type Audit struct {
+	CreatedBy string `json:"created_by" mapper:"meta.created_by"`
+	UpdatedBy string `json:"updated_by" mapper:"meta.updated_by"`
+}
+
+type Meta struct {
+	Version int    `json:"version" mapper:"meta.version"`
+	Source  string `json:"source" mapper:"meta.source"`
+}
+
+type auditedOrder struct {
+	Audit
+	*Meta
+	Number string `json:"number" mapper:"order_number"`
+}
+
+const auditedOrderJson = `{"order_number":"A-1","meta":{"created_by":"alice","updated_by":"bob","version":3,"source":"web"}}`
+
+func (s *MapperSuite) TestEmbeddedStructTags() {
+	dest := auditedOrder{}
+	err := pkg.Unmarshal([]byte(auditedOrderJson), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "A-1", dest.Number)
+	require.Equal(s.T(), "alice", dest.CreatedBy)
+	require.Equal(s.T(), "bob", dest.UpdatedBy)
+	require.NotNil(s.T(), dest.Meta)
+	require.Equal(s.T(), 3, dest.Version)
+	require.Equal(s.T(), "web", dest.Source)
+
+	bytes, err := pkg.Marshal(dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "alice", gjsonGet(bytes, "meta.created_by"))
+	require.Equal(s.T(), "bob", gjsonGet(bytes, "meta.updated_by"))
+	require.Equal(s.T(), "3", gjsonGet(bytes, "meta.version"))
+	require.Equal(s.T(), "web", gjsonGet(bytes, "meta.source"))
+	require.Equal(s.T(), "A-1", gjsonGet(bytes, "order_number"))
+}
+
+func (s *MapperSuite) TestNilEmbeddedPointer() {
+	source := auditedOrder{Number: "A-2", Audit: Audit{CreatedBy: "carol"}}
+	bytes, err := pkg.Marshal(source)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "carol", gjsonGet(bytes, "meta.created_by"))
+	require.False(s.T(), gjsonExists(bytes, "meta.version"))
+}
+
+func (s *MapperSuite) TestEmbeddedFieldShadowing() {
+	type shadowed struct {
+		Audit
+		// the outer field wins over the promoted one with the same json name
+		CreatedBy string `json:"created_by" mapper:"author"`
+	}
+	dest := shadowed{}
+	err := pkg.Unmarshal([]byte(`{"author":"dave","meta":{"created_by":"alice","updated_by":"bob"}}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "dave", dest.CreatedBy)
+	require.Equal(s.T(), "", dest.Audit.CreatedBy)
+	require.Equal(s.T(), "bob", dest.UpdatedBy)
+}
+
+func (s *MapperSuite) TestConflictingEmbeddedFields() {
+	type firstOwner struct {
+		Owner string `mapper:"first.owner"`
+	}
+	type secondOwner struct {
+		Owner string `mapper:"second.owner"`
+	}
+	type conflicting struct {
+		firstOwner
+		secondOwner
+		Audit
+	}
+	dest := conflicting{}
+	err := pkg.Unmarshal([]byte(`{"first":{"owner":"alice"},"second":{"owner":"erin"},"meta":{"created_by":"bob","updated_by":"bob"}}`), &dest)
+	require.NoError(s.T(), err)
+	// encoding/json drops fields that conflict at the same depth, so do we
+	require.Equal(s.T(), "", dest.firstOwner.Owner)
+	require.Equal(s.T(), "", dest.secondOwner.Owner)
+	require.Equal(s.T(), "bob", dest.CreatedBy)
+}
+
+func (s *MapperSuite) TestUnexportedEmbeddedStruct() {
+	type audit struct {
+		CreatedBy string `json:"created_by" mapper:"meta.created_by"`
+	}
+	type withUnexported struct {
+		audit
+		Number string `json:"number"`
+	}
+	dest := withUnexported{}
+	err := pkg.Unmarshal([]byte(`{"number":"A-3","meta":{"created_by":"frank"}}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "frank", dest.CreatedBy)
+	require.Equal(s.T(), "A-3", dest.Number)
+}
+
+func (s *MapperSuite) TestEmbeddedStructWithJsonName() {
+	type named struct {
+		Audit `json:"audit"`
+	}
+	dest := named{}
+	err := pkg.Unmarshal([]byte(`{"audit":{"meta":{"created_by":"grace","updated_by":"grace"}}}`), &dest)
+	require.NoError(s.T(), err)
+	// a named embedded struct is not promoted, its tags are relative to its own object
+	require.Equal(s.T(), "grace", dest.CreatedBy)
+}
@@ -0,0 +1,106 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"testing"
+)
+
+// countingWriter records the size of every write
+type countingWriter struct {
+	bytes.Buffer
+	writes []int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, len(p))
+	return w.Buffer.Write(p)
+}
+
+// requireEncodeLikeMarshal checks Encode writes what Marshal returns, followed by a newline
+func (s *MapperSuite) requireEncodeLikeMarshal(v any) {
+	var out bytes.Buffer
+	require.NoError(s.T(), pkg.NewEncoder(&out).Encode(v))
+	require.Equal(s.T(), string(must(pkg.Marshal(v)))+"\n", out.String())
+}
+
+func (s *MapperSuite) TestEncodeLikeMarshal() {
+	mapped := getRandomMappedStructs(20)
+	s.requireEncodeLikeMarshal(mapped)
+	s.requireEncodeLikeMarshal(&mapped)
+	s.requireEncodeLikeMarshal(getRandomNonMappedStructPointers(3))
+	s.requireEncodeLikeMarshal([2]mappedStruct{getRandomMappedStruct(), getRandomMappedStruct()})
+	s.requireEncodeLikeMarshal(mapped[0])
+	s.requireEncodeLikeMarshal([]mappedStruct{})
+	s.requireEncodeLikeMarshal([]mappedStruct(nil))
+	s.requireEncodeLikeMarshal([]byte("<bytes>"))
+	s.requireEncodeLikeMarshal([]string{"<a>", "b&c"})
+	s.requireEncodeLikeMarshal(nil)
+}
+
+func (s *MapperSuite) TestEncodeNDJSON() {
+	lines := []erroringLine{{Sku: "a", Quantity: 1}, {Sku: "b", Quantity: 2}}
+	var out bytes.Buffer
+	encoder := pkg.NewEncoder(&out)
+	encoder.SetNDJSON(true)
+	require.NoError(s.T(), encoder.Encode(lines))
+	require.NoError(s.T(), encoder.Encode([]erroringLine(nil)))
+	require.NoError(s.T(), encoder.Encode(lines[0]))
+
+	written := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	require.Len(s.T(), written, 3)
+	for i, line := range append(lines, lines[0]) {
+		require.Equal(s.T(), string(must(pkg.Marshal(line))), written[i])
+	}
+
+	decoder := pkg.NewDecoder(&out)
+	decoder.SetNDJSON(true)
+	var decoded []erroringLine
+	require.NoError(s.T(), decoder.Decode(&decoded))
+	require.Equal(s.T(), append(lines, lines[0]), decoded)
+}
+
+func (s *MapperSuite) TestEncodeWritesIncrementally() {
+	mapped := getRandomMappedStructs(500)
+	out := &countingWriter{}
+	require.NoError(s.T(), pkg.NewEncoder(out).Encode(mapped))
+	require.Equal(s.T(), string(must(pkg.Marshal(mapped)))+"\n", out.String())
+	require.Greater(s.T(), len(out.writes), 1)
+	for _, size := range out.writes {
+		// the buffer is flushed once it holds a little more than 32KB
+		require.Less(s.T(), size, 40*1024)
+	}
+}
+
+func (s *MapperSuite) TestEncodeErrors() {
+	lines := []hookedLine{{Quantity: 1}, {Quantity: -1}, {Quantity: 2}}
+	mapper := pkg.New(pkg.WithErrorAggregation())
+	marshaled, marshalErr := mapper.Marshal(lines)
+
+	var out bytes.Buffer
+	encodeErr := mapper.NewEncoder(&out).Encode(lines)
+	require.Error(s.T(), encodeErr)
+	require.EqualError(s.T(), encodeErr, marshalErr.Error())
+	require.Equal(s.T(), string(marshaled)+"\n", out.String())
+	var many pkg.MappingErrors
+	require.True(s.T(), errors.As(encodeErr, &many))
+	require.Equal(s.T(), 1, many[0].Index)
+
+	// failing fast stops before anything buffered is written
+	out.Reset()
+	require.Error(s.T(), pkg.NewEncoder(&out).Encode(lines))
+	require.Empty(s.T(), out.String())
+}
+
+func BenchmarkEncode(b *testing.B) {
+	mapped := getRandomMappedStructs(100)
+	for n := 0; n < b.N; n++ {
+		if err := pkg.NewEncoder(io.Discard).Encode(mapped); err != nil {
+			panic(err)
+		}
+	}
+}
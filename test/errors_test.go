@@ -0,0 +1,104 @@
+package test
+
+import (
+	"errors"
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/joomcode/errorx"
+	"github.com/stretchr/testify/require"
+	"time"
+)
+
+type erroringLine struct {
+	Sku      string    `json:"sku" mapper:"product.sku"`
+	Quantity int       `json:"quantity" mapper:"qty"`
+	Shipped  time.Time `json:"shipped" mapper:"shipped_on,coerce,layout=DateOnly"`
+}
+
+type erroringOrder struct {
+	Number string         `json:"number" mapper:"order_number"`
+	Lines  []erroringLine `json:"lines" mapper:"line_items"`
+}
+
+func (s *MapperSuite) TestMappingErrorLocation() {
+	data := []byte(`{"order_number":"A-1","line_items":[
+		{"product":{"sku":"a"},"qty":1,"shipped_on":"2023-03-17"},
+		{"product":{"sku":"b"},"qty":2,"shipped_on":"yesterday"}
+	]}`)
+	err := pkg.Unmarshal(data, &erroringOrder{})
+	require.Error(s.T(), err)
+
+	var mappingErr *pkg.MappingError
+	require.True(s.T(), errors.As(err, &mappingErr))
+	require.Equal(s.T(), "Lines[1].Shipped", mappingErr.Field)
+	require.Equal(s.T(), "shipped_on", mappingErr.MapperPath)
+	require.Equal(s.T(), "line_items.1.shipped_on", mappingErr.JSONPath)
+	require.Equal(s.T(), 1, mappingErr.Index)
+	require.Contains(s.T(), err.Error(), "field Lines[1].Shipped, path line_items.1.shipped_on, index 1")
+	// the errorx classification of the cause survives the wrapping
+	require.True(s.T(), errorx.IsOfType(err, errorx.IllegalFormat))
+}
+
+func (s *MapperSuite) TestMappingErrorFromJsonDecoding() {
+	err := pkg.Unmarshal([]byte(`{"order_number":"A-1","line_items":[{"product":{"sku":"a"},"qty":"one"}]}`), &erroringOrder{})
+	require.Error(s.T(), err)
+
+	var mappingErr *pkg.MappingError
+	require.True(s.T(), errors.As(err, &mappingErr))
+	require.Equal(s.T(), "Lines[0].Quantity", mappingErr.Field)
+	require.Equal(s.T(), "line_items.0.qty", mappingErr.JSONPath)
+	require.Equal(s.T(), 0, mappingErr.Index)
+}
+
+func (s *MapperSuite) TestFailFastStopsAtFirstElement() {
+	data := []byte(`[{"qty":"one"},{"qty":"two"}]`)
+	err := pkg.Unmarshal(data, &[]erroringLine{})
+	require.Error(s.T(), err)
+	var many pkg.MappingErrors
+	require.False(s.T(), errors.As(err, &many))
+	var mappingErr *pkg.MappingError
+	require.True(s.T(), errors.As(err, &mappingErr))
+	require.Equal(s.T(), 0, mappingErr.Index)
+}
+
+func (s *MapperSuite) TestErrorAggregation() {
+	mapper := pkg.New(pkg.WithErrorAggregation())
+	data := []byte(`[
+		{"product":{"sku":"a"},"qty":"one","shipped_on":"2023-03-17"},
+		{"product":{"sku":"b"},"qty":2,"shipped_on":"2023-03-18"},
+		{"product":{"sku":"c"},"qty":3,"shipped_on":"tomorrow"}
+	]`)
+	lines := []erroringLine{}
+	err := mapper.Unmarshal(data, &lines)
+	require.Error(s.T(), err)
+
+	var many pkg.MappingErrors
+	require.True(s.T(), errors.As(err, &many))
+	require.Len(s.T(), many, 2)
+	require.Equal(s.T(), "[0].Quantity", many[0].Field)
+	require.Equal(s.T(), "0.qty", many[0].JSONPath)
+	require.Equal(s.T(), "[2].Shipped", many[1].Field)
+	require.Equal(s.T(), "2.shipped_on", many[1].JSONPath)
+	require.Equal(s.T(), 2, many[1].Index)
+	require.True(s.T(), errors.Is(err, many[1].Err))
+
+	// the elements that mapped fine are still there
+	require.Len(s.T(), lines, 3)
+	require.Equal(s.T(), "b", lines[1].Sku)
+	require.Equal(s.T(), 2, lines[1].Quantity)
+}
+
+func (s *MapperSuite) TestErrorAggregationWithinStruct() {
+	type dest struct {
+		First  time.Time     `json:"first" mapper:"a,coerce"`
+		Second time.Duration `json:"second" mapper:"b,coerce"`
+		Third  string        `json:"third" mapper:"c"`
+	}
+	theDest := dest{}
+	err := pkg.New(pkg.WithErrorAggregation()).Unmarshal([]byte(`{"a":"not a time","b":"not a duration","c":"fine"}`), &theDest)
+	var many pkg.MappingErrors
+	require.True(s.T(), errors.As(err, &many))
+	require.Len(s.T(), many, 2)
+	require.Equal(s.T(), "First", many[0].Field)
+	require.Equal(s.T(), "Second", many[1].Field)
+	require.Equal(s.T(), "fine", theDest.Third)
+}
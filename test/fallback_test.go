@@ -0,0 +1,79 @@
+package test
+
+import (
+	"errors"
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/joomcode/errorx"
+	"github.com/stretchr/testify/require"
+)
+
+type versionedUser struct {
+	Email string `json:"email" mapper:"user.email|contact.primaryEmail"`
+	Age   int    `json:"age" mapper:"user.age|profile.age,strict,default=18"`
+	Name  string `json:"name" mapper:"user.name|profile.name|name,required"`
+}
+
+func (s *MapperSuite) TestFallbackPathsUseFirstHit() {
+	dest := versionedUser{}
+	err := pkg.Unmarshal([]byte(`{"user":{"email":"a@example.com","name":"Ann"},"contact":{"primaryEmail":"b@example.com"}}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "a@example.com", dest.Email)
+	require.Equal(s.T(), "Ann", dest.Name)
+
+	dest = versionedUser{}
+	err = pkg.Unmarshal([]byte(`{"contact":{"primaryEmail":"b@example.com"},"profile":{"age":"42"},"name":"Bob"}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "b@example.com", dest.Email)
+	require.Equal(s.T(), 42, dest.Age)
+	require.Equal(s.T(), "Bob", dest.Name)
+}
+
+func (s *MapperSuite) TestFallbackPathsSkipNulls() {
+	dest := versionedUser{}
+	err := pkg.Unmarshal([]byte(`{"user":{"email":null,"name":"Ann"},"contact":{"primaryEmail":"b@example.com"}}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "b@example.com", dest.Email)
+}
+
+func (s *MapperSuite) TestFallbackPathsDefaultAndRequired() {
+	dest := versionedUser{}
+	err := pkg.Unmarshal([]byte(`{"user":{"name":"Ann","age":null}}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 18, dest.Age)
+
+	err = pkg.Unmarshal([]byte(`{"user":{}}`), &versionedUser{})
+	require.Error(s.T(), err)
+	var mappingErr *pkg.MappingError
+	require.True(s.T(), errors.As(err, &mappingErr))
+	require.True(s.T(), errorx.IsOfType(mappingErr.Err, pkg.RequiredFieldMissing))
+	require.Equal(s.T(), "user.name|profile.name|name", mappingErr.MapperPath)
+}
+
+func (s *MapperSuite) TestFallbackPathsErrorReportsPathRead() {
+	err := pkg.Unmarshal([]byte(`{"profile":{"age":"old"},"name":"Bob"}`), &versionedUser{})
+	require.Error(s.T(), err)
+	var mappingErr *pkg.MappingError
+	require.True(s.T(), errors.As(err, &mappingErr))
+	require.Equal(s.T(), "Age", mappingErr.Field)
+	require.Equal(s.T(), "user.age|profile.age", mappingErr.MapperPath)
+	require.Equal(s.T(), "profile.age", mappingErr.JSONPath)
+}
+
+func (s *MapperSuite) TestFallbackPathsMarshalToFirstPath() {
+	data, err := pkg.Marshal(versionedUser{Email: "a@example.com", Age: 30, Name: "Ann"})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "a@example.com", gjsonGet(data, "user.email"))
+	require.Equal(s.T(), "30", gjsonGet(data, "user.age"))
+	require.False(s.T(), gjsonExists(data, "contact"))
+	require.False(s.T(), gjsonExists(data, "profile"))
+}
+
+func (s *MapperSuite) TestFallbackPathsKeepQueriesWhole() {
+	type tagged struct {
+		Value string `json:"value" mapper:"items.#(kind==\"a|b\").value|value"`
+	}
+	dest := tagged{}
+	err := pkg.Unmarshal([]byte(`{"items":[{"kind":"a|b","value":"found"}]}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "found", dest.Value)
+}
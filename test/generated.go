@@ -0,0 +1,38 @@
+package test
+
+import (
+	"strings"
+)
+
+//go:generate go run ../cmd/mappergen -type generatedOrder,generatedLine -output generated_mapper.go
+
+// generatedOrder and generatedLine have generated mapping code, generatedCustomer is mapped with reflection
+type generatedOrder struct {
+	ID       string             `json:"id" mapper:"order.id"`
+	Total    float64            `json:"total" mapper:"amounts.total,coerce"`
+	Quantity int16              `json:"quantity" mapper:"qty,coerce"`
+	Code     int                `json:"code" mapper:"code,string"`
+	Note     string             `json:"note" mapper:"meta.note,omitempty"`
+	Paid     *bool              `json:"paid" mapper:"flags.paid,coerce"`
+	Status   generatedStatus    `json:"status" mapper:"state,coerce"`
+	Lines    []generatedLine    `json:"lines"`
+	Customer *generatedCustomer `json:"customer"`
+	Comment  string             `json:"comment"`
+}
+
+type generatedLine struct {
+	Sku   string `json:"sku" mapper:"product.sku"`
+	Count uint8  `json:"count" mapper:"quantity,coerce"`
+}
+
+type generatedCustomer struct {
+	Name string `json:"name" mapper:"full_name"`
+}
+
+type generatedStatus string
+
+// AfterMap runs around the generated code like it runs around reflection
+func (l *generatedLine) AfterMap() error {
+	l.Sku = strings.TrimSpace(l.Sku)
+	return nil
+}
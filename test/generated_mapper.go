@@ -0,0 +1,277 @@
+// Code generated by mappergen; DO NOT EDIT.
+
+package test
+
+import (
+	"encoding/json"
+	mapper "github.com/catalystcommunity/mapper/pkg"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"strconv"
+)
+
+// MarshalMapped returns the json encoding of v with every mapper tagged field written to its mapped path
+func (v generatedLine) MarshalMapped(m *mapper.Mapper) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([][]byte, 2)
+	if result := gjson.GetBytes(data, "sku"); result.Exists() {
+		value := result.Raw
+		changes[0] = []byte(value)
+	}
+	if result := gjson.GetBytes(data, "count"); result.Exists() {
+		value := strconv.FormatUint(uint64(uint8(result.Uint())), 10)
+		changes[1] = []byte(value)
+	}
+	if changes[0] != nil {
+		if data, err = sjson.SetRawBytes(data, "product.sku", changes[0]); err != nil {
+			return nil, mapper.GeneratedFieldError(err, "Sku", "product.sku", "product.sku")
+		}
+	}
+	if changes[1] != nil {
+		if data, err = sjson.SetRawBytes(data, "quantity", changes[1]); err != nil {
+			return nil, mapper.GeneratedFieldError(err, "Count", "quantity", "quantity")
+		}
+	}
+	return data, nil
+}
+
+// UnmarshalMapped parses data into v, reading every mapper tagged field from its mapped path
+func (v *generatedLine) UnmarshalMapped(m *mapper.Mapper, data []byte) error {
+	var err error
+	changes := make([][]byte, 2)
+	if result := gjson.GetBytes(data, "product.sku"); result.Exists() {
+		value := result.Raw
+		changes[0] = []byte(value)
+	}
+	if result := gjson.GetBytes(data, "quantity"); result.Exists() {
+		value := strconv.FormatUint(uint64(uint8(result.Uint())), 10)
+		changes[1] = []byte(value)
+	}
+	if changes[0] != nil {
+		if data, err = sjson.SetRawBytes(data, "sku", changes[0]); err != nil {
+			return mapper.GeneratedFieldError(err, "Sku", "product.sku", "sku")
+		}
+	}
+	if changes[1] != nil {
+		if data, err = sjson.SetRawBytes(data, "count", changes[1]); err != nil {
+			return mapper.GeneratedFieldError(err, "Count", "quantity", "count")
+		}
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return mapper.GeneratedUnmarshalError(err, v)
+	}
+	return nil
+}
+
+// MarshalMapped returns the json encoding of v with every mapper tagged field written to its mapped path
+func (v generatedOrder) MarshalMapped(m *mapper.Mapper) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if gjson.GetBytes(data, "lines").Exists() {
+		nested, err := m.Marshal(v.Lines)
+		if err == nil {
+			data, err = sjson.SetRawBytes(data, "lines", nested)
+		}
+		if err != nil {
+			return nil, mapper.GeneratedNestedError(err, "Lines", "lines")
+		}
+	}
+	if gjson.GetBytes(data, "customer").Exists() {
+		nested, err := m.Marshal(v.Customer)
+		if err == nil {
+			data, err = sjson.SetRawBytes(data, "customer", nested)
+		}
+		if err != nil {
+			return nil, mapper.GeneratedNestedError(err, "Customer", "customer")
+		}
+	}
+	changes := make([][]byte, 7)
+	if result := gjson.GetBytes(data, "id"); result.Exists() {
+		value := result.Raw
+		changes[0] = []byte(value)
+	}
+	if result := gjson.GetBytes(data, "total"); result.Exists() {
+		encoded, err := json.Marshal(result.Float())
+		if err != nil {
+			return nil, mapper.GeneratedFieldError(err, "Total", "amounts.total", "amounts.total")
+		}
+		value := string(encoded)
+		changes[1] = []byte(value)
+	}
+	if result := gjson.GetBytes(data, "quantity"); result.Exists() {
+		value := strconv.FormatInt(int64(int16(result.Int())), 10)
+		changes[2] = []byte(value)
+	}
+	if result := gjson.GetBytes(data, "code"); result.Exists() {
+		value := result.String()
+		changes[3] = []byte(value)
+	}
+	if result := gjson.GetBytes(data, "note"); result.Exists() {
+		value := result.Raw
+		if value == "" {
+			if data, err = sjson.DeleteBytes(data, "note"); err != nil {
+				return nil, mapper.GeneratedFieldError(err, "Note", "meta.note", "note")
+			}
+		} else {
+			changes[4] = []byte(value)
+		}
+	}
+	if result := gjson.GetBytes(data, "paid"); result.Exists() {
+		value := strconv.FormatBool(result.Bool())
+		changes[5] = []byte(value)
+	}
+	if result := gjson.GetBytes(data, "status"); result.Exists() {
+		encoded, err := json.Marshal(result.String())
+		if err != nil {
+			return nil, mapper.GeneratedFieldError(err, "Status", "state", "state")
+		}
+		value := string(encoded)
+		changes[6] = []byte(value)
+	}
+	if changes[0] != nil {
+		if data, err = sjson.SetRawBytes(data, "order.id", changes[0]); err != nil {
+			return nil, mapper.GeneratedFieldError(err, "ID", "order.id", "order.id")
+		}
+	}
+	if changes[1] != nil {
+		if data, err = sjson.SetRawBytes(data, "amounts.total", changes[1]); err != nil {
+			return nil, mapper.GeneratedFieldError(err, "Total", "amounts.total", "amounts.total")
+		}
+	}
+	if changes[2] != nil {
+		if data, err = sjson.SetRawBytes(data, "qty", changes[2]); err != nil {
+			return nil, mapper.GeneratedFieldError(err, "Quantity", "qty", "qty")
+		}
+	}
+	if changes[3] != nil {
+		if data, err = sjson.SetRawBytes(data, "code", changes[3]); err != nil {
+			return nil, mapper.GeneratedFieldError(err, "Code", "code", "code")
+		}
+	}
+	if changes[4] != nil {
+		if data, err = sjson.SetRawBytes(data, "meta.note", changes[4]); err != nil {
+			return nil, mapper.GeneratedFieldError(err, "Note", "meta.note", "meta.note")
+		}
+	}
+	if changes[5] != nil {
+		if data, err = sjson.SetRawBytes(data, "flags.paid", changes[5]); err != nil {
+			return nil, mapper.GeneratedFieldError(err, "Paid", "flags.paid", "flags.paid")
+		}
+	}
+	if changes[6] != nil {
+		if data, err = sjson.SetRawBytes(data, "state", changes[6]); err != nil {
+			return nil, mapper.GeneratedFieldError(err, "Status", "state", "state")
+		}
+	}
+	return data, nil
+}
+
+// UnmarshalMapped parses data into v, reading every mapper tagged field from its mapped path
+func (v *generatedOrder) UnmarshalMapped(m *mapper.Mapper, data []byte) error {
+	var err error
+	changes := make([][]byte, 7)
+	if result := gjson.GetBytes(data, "order.id"); result.Exists() {
+		value := result.Raw
+		changes[0] = []byte(value)
+	}
+	if result := gjson.GetBytes(data, "amounts.total"); result.Exists() {
+		encoded, err := json.Marshal(result.Float())
+		if err != nil {
+			return mapper.GeneratedFieldError(err, "Total", "amounts.total", "amounts.total")
+		}
+		value := string(encoded)
+		changes[1] = []byte(value)
+	}
+	if result := gjson.GetBytes(data, "qty"); result.Exists() {
+		value := strconv.FormatInt(int64(int16(result.Int())), 10)
+		changes[2] = []byte(value)
+	}
+	if result := gjson.GetBytes(data, "code"); result.Exists() {
+		value := result.String()
+		changes[3] = []byte(value)
+	}
+	if result := gjson.GetBytes(data, "meta.note"); result.Exists() {
+		value := result.Raw
+		if value != "" {
+			changes[4] = []byte(value)
+		}
+	}
+	if result := gjson.GetBytes(data, "flags.paid"); result.Exists() {
+		value := strconv.FormatBool(result.Bool())
+		changes[5] = []byte(value)
+	}
+	if result := gjson.GetBytes(data, "state"); result.Exists() {
+		encoded, err := json.Marshal(result.String())
+		if err != nil {
+			return mapper.GeneratedFieldError(err, "Status", "state", "state")
+		}
+		value := string(encoded)
+		changes[6] = []byte(value)
+	}
+	if changes[0] != nil {
+		if data, err = sjson.SetRawBytes(data, "id", changes[0]); err != nil {
+			return mapper.GeneratedFieldError(err, "ID", "order.id", "id")
+		}
+	}
+	if changes[1] != nil {
+		if data, err = sjson.SetRawBytes(data, "total", changes[1]); err != nil {
+			return mapper.GeneratedFieldError(err, "Total", "amounts.total", "total")
+		}
+	}
+	if changes[2] != nil {
+		if data, err = sjson.SetRawBytes(data, "quantity", changes[2]); err != nil {
+			return mapper.GeneratedFieldError(err, "Quantity", "qty", "quantity")
+		}
+	}
+	if changes[3] != nil {
+		if data, err = sjson.SetRawBytes(data, "code", changes[3]); err != nil {
+			return mapper.GeneratedFieldError(err, "Code", "code", "code")
+		}
+	}
+	if changes[4] != nil {
+		if data, err = sjson.SetRawBytes(data, "note", changes[4]); err != nil {
+			return mapper.GeneratedFieldError(err, "Note", "meta.note", "note")
+		}
+	}
+	if changes[5] != nil {
+		if data, err = sjson.SetRawBytes(data, "paid", changes[5]); err != nil {
+			return mapper.GeneratedFieldError(err, "Paid", "flags.paid", "paid")
+		}
+	}
+	if changes[6] != nil {
+		if data, err = sjson.SetRawBytes(data, "status", changes[6]); err != nil {
+			return mapper.GeneratedFieldError(err, "Status", "state", "status")
+		}
+	}
+	nested0 := gjson.GetBytes(data, "lines")
+	if nested0.Exists() {
+		if data, err = sjson.DeleteBytes(data, "lines"); err != nil {
+			return err
+		}
+	}
+	nested1 := gjson.GetBytes(data, "customer")
+	if nested1.Exists() {
+		if data, err = sjson.DeleteBytes(data, "customer"); err != nil {
+			return err
+		}
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return mapper.GeneratedUnmarshalError(err, v)
+	}
+	if nested0.Exists() {
+		if err = m.Unmarshal([]byte(nested0.Raw), &v.Lines); err != nil {
+			return mapper.GeneratedNestedError(err, "Lines", "lines")
+		}
+	}
+	if nested1.Exists() {
+		if err = m.Unmarshal([]byte(nested1.Raw), &v.Customer); err != nil {
+			return mapper.GeneratedNestedError(err, "Customer", "customer")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package test
+
+import (
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const generatedPayload = `{
+	"order": {"id": "A-1"},
+	"amounts": {"total": "19.99"},
+	"qty": "70000",
+	"code": "42",
+	"meta": {"note": "rush"},
+	"flags": {"paid": "true"},
+	"state": 3,
+	"lines": [{"product": {"sku": "x"}, "quantity": "2.7"}, {"product": {"sku": "y"}}],
+	"customer": {"full_name": "Ann"},
+	"comment": "hi"
+}`
+
+// reflectionMapper doesn't run generated code, aggregation only changes how several errors are returned
+var reflectionMapper = pkg.New(pkg.WithErrorAggregation())
+
+func (s *MapperSuite) TestGeneratedUnmarshalParity() {
+	for _, payload := range []string{generatedPayload, `{}`, `{"amounts": {"total": null}, "lines": null, "state": {"a": 1}}`} {
+		var generated, reflected generatedOrder
+		require.NoError(s.T(), pkg.Unmarshal([]byte(payload), &generated))
+		require.NoError(s.T(), reflectionMapper.Unmarshal([]byte(payload), &reflected))
+		require.Equal(s.T(), reflected, generated)
+	}
+}
+
+func (s *MapperSuite) TestGeneratedMarshalParity() {
+	paid := true
+	orders := []generatedOrder{
+		{},
+		{ID: "A-1", Total: 19.99, Quantity: -3, Code: 42, Note: "rush", Paid: &paid, Status: "open",
+			Lines: []generatedLine{{Sku: "x", Count: 2}}, Customer: &generatedCustomer{Name: "Ann"}, Comment: "hi"},
+	}
+	for _, order := range orders {
+		generated, err := pkg.Marshal(order)
+		require.NoError(s.T(), err)
+		reflected, err := reflectionMapper.Marshal(order)
+		require.NoError(s.T(), err)
+		require.JSONEq(s.T(), string(reflected), string(generated))
+	}
+}
+
+func (s *MapperSuite) TestGeneratedErrors() {
+	// warning about unknown fields runs reflection and keeps errors as they are
+	warning := pkg.New(pkg.WithUnknownFields(pkg.WarnUnknownFields, func(string) {}))
+	var generated, reflected generatedOrder
+	payload := []byte(`{"code": "abc", "comment": 5}`)
+	generatedErr := pkg.Unmarshal(payload, &generated)
+	reflectedErr := warning.Unmarshal(payload, &reflected)
+	require.Error(s.T(), generatedErr)
+	require.EqualError(s.T(), generatedErr, reflectedErr.Error())
+
+	payload = []byte(`{"lines": [{"product": {"sku": 1}}]}`)
+	generatedErr = pkg.Unmarshal(payload, &generated)
+	reflectedErr = warning.Unmarshal(payload, &reflected)
+	require.Error(s.T(), generatedErr)
+	require.EqualError(s.T(), generatedErr, reflectedErr.Error())
+}
+
+type handMapped struct {
+	Name string `json:"name" mapper:"full_name"`
+}
+
+func (h handMapped) MarshalMapped(m *pkg.Mapper) ([]byte, error) {
+	return []byte(`{"generated":true}`), nil
+}
+
+func (h *handMapped) UnmarshalMapped(m *pkg.Mapper, data []byte) error {
+	h.Name = "generated"
+	return nil
+}
+
+func (s *MapperSuite) TestGeneratedMethodsAreDetected() {
+	data, err := pkg.Marshal(handMapped{Name: "Ann"})
+	require.NoError(s.T(), err)
+	require.JSONEq(s.T(), `{"generated":true}`, string(data))
+
+	var mapped []handMapped
+	require.NoError(s.T(), pkg.Unmarshal([]byte(`[{"full_name": "Ann"}]`), &mapped))
+	require.Equal(s.T(), []handMapped{{Name: "generated"}}, mapped)
+}
+
+func (s *MapperSuite) TestGeneratedMethodsNeedDefaultOptions() {
+	for _, mapper := range []*pkg.Mapper{pkg.New(pkg.WithTagName("other")), pkg.New(pkg.WithStrictCoercion()), reflectionMapper} {
+		data, err := mapper.Marshal(handMapped{Name: "Ann"})
+		require.NoError(s.T(), err)
+		require.NotContains(s.T(), string(data), "generated")
+
+		var mapped handMapped
+		require.NoError(s.T(), mapper.Unmarshal([]byte(`{"name": "Ann"}`), &mapped))
+		require.Equal(s.T(), "Ann", mapped.Name)
+	}
+}
+
+type handCoerced struct {
+	Count int `json:"count" mapper:"qty,coerce"`
+}
+
+func (h handCoerced) MarshalMapped(m *pkg.Mapper) ([]byte, error) {
+	return []byte(`{"generated":true}`), nil
+}
+
+func (s *MapperSuite) TestGeneratedMethodsIgnoreUnrelatedConverters() {
+	toPoint := func(result gjson.Result) (any, error) {
+		return geoPoint{}, nil
+	}
+	pkg.RegisterConverter(gjson.String, reflect.TypeOf(geoPoint{}), toPoint)
+	s.T().Cleanup(func() {
+		pkg.UnregisterConverter(gjson.String, reflect.TypeOf(geoPoint{}))
+	})
+	data, err := pkg.Marshal(handCoerced{Count: 1})
+	require.NoError(s.T(), err)
+	require.JSONEq(s.T(), `{"generated":true}`, string(data))
+
+	// a converter for a coerced field's type changes how it is mapped, so reflection maps it
+	mapper := pkg.New(pkg.WithConverter(gjson.Number, reflect.TypeOf(0), func(result gjson.Result) (any, error) {
+		return result.Int() * 2, nil
+	}))
+	data, err = mapper.Marshal(handCoerced{Count: 1})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "2", gjsonGet(data, "qty"))
+}
+
+func (s *MapperSuite) TestGeneratedCodeIsUpToDate() {
+	if testing.Short() {
+		s.T().Skip("runs the generator")
+	}
+	output := filepath.Join(s.T().TempDir(), "generated_mapper.go")
+	cmd := exec.Command("go", "run", "../cmd/mappergen", "-type", "generatedOrder,generatedLine", "-output", output)
+	out, err := cmd.CombinedOutput()
+	require.NoError(s.T(), err, string(out))
+
+	expected, err := os.ReadFile("generated_mapper.go")
+	require.NoError(s.T(), err)
+	actual, err := os.ReadFile(output)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), string(expected), string(actual), "run go generate ./test")
+}
+
+func (s *MapperSuite) TestGeneratorRejectsUnsupportedTags() {
+	if testing.Short() {
+		s.T().Skip("runs the generator")
+	}
+	dir := s.T().TempDir()
+	source := "package orders\n\ntype Order struct {\n\tName string `json:\"name\" mapper:\"full_name,transform=upper\"`\n}\n"
+	require.NoError(s.T(), os.WriteFile(filepath.Join(dir, "orders.go"), []byte(source), 0o644))
+
+	cmd := exec.Command("go", "run", "./cmd/mappergen", dir)
+	cmd.Dir = ".."
+	out, err := cmd.CombinedOutput()
+	require.Error(s.T(), err)
+	require.Contains(s.T(), string(out), "Order: field Name: option transform=upper isn't supported by generated code")
+	_, err = os.Stat(filepath.Join(dir, "mapper_gen.go"))
+	require.True(s.T(), os.IsNotExist(err))
+}
+
+func BenchmarkGeneratedUnmarshal(b *testing.B) {
+	payload := []byte(generatedPayload)
+	for n := 0; n < b.N; n++ {
+		var order generatedOrder
+		if err := pkg.Unmarshal(payload, &order); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func BenchmarkReflectionUnmarshal(b *testing.B) {
+	payload := []byte(generatedPayload)
+	for n := 0; n < b.N; n++ {
+		var order generatedOrder
+		if err := reflectionMapper.Unmarshal(payload, &order); err != nil {
+			panic(err)
+		}
+	}
+}
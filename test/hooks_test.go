@@ -0,0 +1,174 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/joomcode/errorx"
+	"github.com/stretchr/testify/require"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type hookedLine struct {
+	Sku      string    `json:"sku" mapper:"product.sku"`
+	Quantity int       `json:"quantity" mapper:"qty"`
+	Price    float64   `json:"price"`
+	Total    float64   `json:"total"`
+	Tags     []hookTag `json:"tags"`
+}
+
+// BeforeMap computes the total that is only ever written
+func (l *hookedLine) BeforeMap() error {
+	if l.Quantity < 0 {
+		return errorx.IllegalArgument.New("negative quantity %d", l.Quantity)
+	}
+	l.Total = float64(l.Quantity) * l.Price
+	return nil
+}
+
+// AfterMap normalizes the sku and checks the quantity
+func (l *hookedLine) AfterMap() error {
+	if l.Quantity < 0 {
+		return errorx.IllegalArgument.New("negative quantity %d", l.Quantity)
+	}
+	l.Sku = strings.ToUpper(strings.TrimSpace(l.Sku))
+	return nil
+}
+
+type hookTag string
+
+func (t *hookTag) AfterMap() error {
+	*t = hookTag(strings.ToLower(string(*t)))
+	return nil
+}
+
+// price maps itself to a string like "12.50 EUR"
+type price struct {
+	Cents    int64
+	Currency string
+}
+
+func (p price) MarshalMapper(m *pkg.Mapper) ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("%d.%02d %s", p.Cents/100, p.Cents%100, p.Currency))
+}
+
+func (p *price) UnmarshalMapper(m *pkg.Mapper, data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	amount, currency, _ := strings.Cut(text, " ")
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return err
+	}
+	p.Cents = int64(math.Round(value * 100))
+	p.Currency = currency
+	return nil
+}
+
+type hookedOrder struct {
+	Lines  []hookedLine          `json:"lines" mapper:"items"`
+	Extra  *hookedLine           `json:"extra"`
+	ByCode map[string]hookedLine `json:"by_code"`
+	Price  price                 `json:"price" mapper:"pricing"`
+}
+
+func (s *MapperSuite) TestAfterMapRunsAtEveryLevel() {
+	data := []byte(`{
+		"items": [{"product": {"sku": " ab "}, "qty": 2, "tags": ["A", "b"]}],
+		"extra": {"product": {"sku": "cd"}},
+		"by_code": {"x": {"product": {"sku": " ef"}}},
+		"pricing": "12.50 EUR"
+	}`)
+	var order hookedOrder
+	require.NoError(s.T(), pkg.Unmarshal(data, &order))
+	require.Equal(s.T(), hookedLine{Sku: "AB", Quantity: 2, Tags: []hookTag{"a", "b"}}, order.Lines[0])
+	require.Equal(s.T(), "CD", order.Extra.Sku)
+	require.Equal(s.T(), "EF", order.ByCode["x"].Sku)
+	require.Equal(s.T(), price{Cents: 1250, Currency: "EUR"}, order.Price)
+
+	var lines []hookedLine
+	require.NoError(s.T(), pkg.Unmarshal([]byte(`[{"product": {"sku": "a"}}, {"product": {"sku": "b"}}]`), &lines))
+	require.Equal(s.T(), []hookedLine{{Sku: "A"}, {Sku: "B"}}, lines)
+}
+
+func (s *MapperSuite) TestBeforeMapRunsAtEveryLevel() {
+	order := hookedOrder{
+		Lines:  []hookedLine{{Sku: "a", Quantity: 2, Price: 1.5}},
+		Extra:  &hookedLine{Sku: "b", Quantity: 1, Price: 3},
+		ByCode: map[string]hookedLine{"x": {Sku: "c", Quantity: 4, Price: 0.5}},
+		Price:  price{Cents: 1205, Currency: "USD"},
+	}
+	data, err := pkg.Marshal(order)
+	require.NoError(s.T(), err)
+	require.JSONEq(s.T(), `{
+		"lines": [{"sku": "a", "quantity": 2, "price": 1.5, "total": 3, "tags": null, "product": {"sku": "a"}, "qty": 2}],
+		"items": [{"sku": "a", "quantity": 2, "price": 1.5, "total": 3, "tags": null, "product": {"sku": "a"}, "qty": 2}],
+		"extra": {"sku": "b", "quantity": 1, "price": 3, "total": 3, "tags": null, "product": {"sku": "b"}, "qty": 1},
+		"by_code": {"x": {"sku": "c", "quantity": 4, "price": 0.5, "total": 2, "tags": null, "product": {"sku": "c"}, "qty": 4}},
+		"price": "12.05 USD",
+		"pricing": "12.05 USD"
+	}`, string(data))
+	// every value is prepared on a copy, what was marshaled stays as it was
+	require.Zero(s.T(), order.Lines[0].Total)
+	require.Zero(s.T(), order.Extra.Total)
+	require.Zero(s.T(), order.ByCode["x"].Total)
+}
+
+func (s *MapperSuite) TestHookErrors() {
+	err := pkg.Unmarshal([]byte(`{"items": [{"qty": 1}, {"qty": -1}]}`), &hookedOrder{})
+	require.Error(s.T(), err)
+	var mappingErr *pkg.MappingError
+	require.True(s.T(), errors.As(err, &mappingErr))
+	require.Equal(s.T(), "Lines[1]", mappingErr.Field)
+	require.Equal(s.T(), 1, mappingErr.Index)
+	require.True(s.T(), errorx.IsOfType(err, errorx.IllegalArgument))
+
+	_, err = pkg.Marshal([]hookedLine{{Quantity: -1}})
+	require.Error(s.T(), err)
+	require.True(s.T(), errorx.IsOfType(err, errorx.IllegalArgument))
+
+	err = pkg.Unmarshal([]byte(`{"pricing": "twelve EUR"}`), &hookedOrder{})
+	require.Error(s.T(), err)
+	require.True(s.T(), errors.As(err, &mappingErr))
+	require.Equal(s.T(), "Price", mappingErr.Field)
+}
+
+func (s *MapperSuite) TestHooksRunWithAnyOptions() {
+	mapper := pkg.New(pkg.WithTagName("other"), pkg.WithStrictCoercion(), pkg.WithUnknownFields(pkg.RejectUnknownFields, nil))
+	data, err := mapper.Marshal(price{Cents: 99, Currency: "EUR"})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), `"0.99 EUR"`, string(data))
+
+	var prices []price
+	require.NoError(s.T(), mapper.Unmarshal([]byte(`["1.5 EUR"]`), &prices))
+	require.Equal(s.T(), []price{{Cents: 150, Currency: "EUR"}}, prices)
+}
+
+func (s *MapperSuite) TestHooksRunOnConvert() {
+	var line hookedLine
+	require.NoError(s.T(), pkg.Convert(struct {
+		Sku string `json:"sku"`
+	}{Sku: " ab "}, &line))
+	require.Equal(s.T(), "AB", line.Sku)
+
+	var total struct {
+		Total float64 `json:"total"`
+	}
+	require.NoError(s.T(), pkg.Convert(hookedLine{Quantity: 3, Price: 2}, &total))
+	require.Equal(s.T(), 6.0, total.Total)
+}
+
+func (s *MapperSuite) TestHooksRunAroundGeneratedCode() {
+	var order generatedOrder
+	require.NoError(s.T(), pkg.Unmarshal([]byte(`{"lines": [{"product": {"sku": " x "}}]}`), &order))
+	require.Equal(s.T(), "x", order.Lines[0].Sku)
+
+	var line generatedLine
+	require.NoError(s.T(), pkg.Unmarshal([]byte(`{"product": {"sku": " y "}}`), &line))
+	require.Equal(s.T(), "y", line.Sku)
+}
@@ -8,7 +8,9 @@ import (
 	"github.com/gobuffalo/nulls"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"github.com/tidwall/gjson"
 	"strconv"
+	"sync"
 	"testing"
 )
 
@@ -419,6 +421,27 @@ func (s *MapperSuite) TestOmitEmptySlice() {
 	require.NoError(s.T(), err)
 }
 
+func (s *MapperSuite) TestConcurrentMapping() {
+	// every goroutine hits the type plan cache at the same time, run with -race to catch unsafe access
+	// require stops the test with FailNow, which only works from the test goroutine, so results are checked after
+	nonMapped := getRandomNonMappedStructs(20)
+	mapped := make([]mappedStruct, len(nonMapped))
+	errs := make([]error, len(nonMapped))
+	wg := sync.WaitGroup{}
+	for i := range nonMapped {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = pkg.Convert(nonMapped[i], &mapped[i])
+		}(i)
+	}
+	wg.Wait()
+	for i := range nonMapped {
+		require.NoError(s.T(), errs[i])
+		s.assertNonMappedStructMappedStructEquality(nonMapped[i], mapped[i])
+	}
+}
+
 func getRandomNonMappedStructPointers(num int) []*nonMappedStruct {
 	structs := []*nonMappedStruct{}
 	for i := 0; i < num; i++ {
@@ -604,3 +627,51 @@ func BenchmarkMarshalUnmarshal(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkMapperMarshalSlice(b *testing.B) {
+	mappedSlice := getRandomMappedStructs(100)
+
+	for n := 0; n < b.N; n++ {
+		_, err := pkg.Marshal(mappedSlice)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+func BenchmarkMapperUnmarshalSlice(b *testing.B) {
+	nonMappedSlice := getRandomNonMappedStructs(100)
+	bytes, err := pkg.Marshal(nonMappedSlice)
+	if err != nil {
+		panic(err)
+	}
+
+	for n := 0; n < b.N; n++ {
+		mappedSlice := []mappedStruct{}
+		err = pkg.Unmarshal(bytes, &mappedSlice)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+func BenchmarkMapperMarshalParallel(b *testing.B) {
+	mapped := getRandomMappedStruct()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, err := pkg.Marshal(mapped)
+			if err != nil {
+				panic(err)
+			}
+		}
+	})
+}
+
+func gjsonGet(data []byte, path string) string {
+	return gjson.GetBytes(data, path).String()
+}
+
+func gjsonExists(data []byte, path string) bool {
+	return gjson.GetBytes(data, path).Exists()
+}
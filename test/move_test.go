@@ -0,0 +1,62 @@
+package test
+
+import (
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/stretchr/testify/require"
+)
+
+type partnerRequest struct {
+	ABool   bool   `json:"a_bool" mapper:"some_other_bool,move"`
+	Name    string `json:"name" mapper:"customer.name"`
+	Count   int    `json:"count" mapper:",coerce,move"`
+	Comment string `json:"comment"`
+}
+
+func (s *MapperSuite) TestMovePerField() {
+	data, err := pkg.Marshal(partnerRequest{ABool: true, Name: "Ann", Count: 3, Comment: "hi"})
+	require.NoError(s.T(), err)
+	require.JSONEq(s.T(), `{"some_other_bool":true,"name":"Ann","customer":{"name":"Ann"},"count":3,"comment":"hi"}`, string(data))
+}
+
+func (s *MapperSuite) TestMoveForMapper() {
+	type copied struct {
+		ID   string `json:"id" mapper:"ref.id,copy"`
+		Name string `json:"name" mapper:"customer.name"`
+	}
+	mapper := pkg.New(pkg.WithMovedFields())
+	data, err := mapper.Marshal(partnerRequest{ABool: true, Name: "Ann", Count: 3, Comment: "hi"})
+	require.NoError(s.T(), err)
+	require.JSONEq(s.T(), `{"some_other_bool":true,"customer":{"name":"Ann"},"count":3,"comment":"hi"}`, string(data))
+
+	data, err = mapper.Marshal(copied{ID: "1", Name: "Ann"})
+	require.NoError(s.T(), err)
+	require.JSONEq(s.T(), `{"id":"1","ref":{"id":"1"},"customer":{"name":"Ann"}}`, string(data))
+}
+
+func (s *MapperSuite) TestMoveRoundTrip() {
+	mapper := pkg.New(pkg.WithMovedFields())
+	source := partnerRequest{ABool: true, Name: "Ann", Count: 3, Comment: "hi"}
+	data, err := mapper.Marshal(source)
+	require.NoError(s.T(), err)
+	dest := partnerRequest{}
+	require.NoError(s.T(), mapper.Unmarshal(data, &dest))
+	require.Equal(s.T(), source, dest)
+}
+
+func (s *MapperSuite) TestMoveNestedAndRemain() {
+	type inner struct {
+		Sku  string         `json:"sku" mapper:"product.sku"`
+		Rest map[string]any `json:"rest" mapper:",remain"`
+	}
+	type outer struct {
+		Items []inner `json:"items" mapper:"order.lines"`
+	}
+	payload := `{"order":{"lines":[{"product":{"sku":"a","color":"red"}}]}}`
+	mapper := pkg.New(pkg.WithMovedFields())
+	dest := outer{}
+	require.NoError(s.T(), mapper.Unmarshal([]byte(payload), &dest))
+	data, err := mapper.Marshal(dest)
+	require.NoError(s.T(), err)
+	// moving makes the round trip exact
+	require.JSONEq(s.T(), payload, string(data))
+}
@@ -0,0 +1,214 @@
+package test
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/stretchr/testify/require"
+	"strings"
+)
+
+type externalAddress struct {
+	Line1 string `json:"line_1"`
+	Zip   string `json:"postal_code"`
+}
+
+type internalAddress struct {
+	Street string `json:"street" mapper:"line_1"`
+	Zip    string `json:"zip" mapper:"postal_code"`
+}
+
+type externalCustomer struct {
+	Name      string                     `json:"name"`
+	Address   externalAddress            `json:"address"`
+	Billing   *externalAddress           `json:"billing"`
+	Previous  []externalAddress          `json:"previous"`
+	Shipping  []*externalAddress         `json:"shipping"`
+	Locations map[string]externalAddress `json:"locations"`
+}
+
+type internalCustomer struct {
+	FullName  string                     `json:"full_name" mapper:"name"`
+	Home      internalAddress            `json:"home" mapper:"address"`
+	Billing   *internalAddress           `json:"billing"`
+	Previous  []internalAddress          `json:"previous"`
+	Shipping  []*internalAddress         `json:"shipping"`
+	Locations map[string]internalAddress `json:"locations"`
+}
+
+func getRandomExternalAddress() externalAddress {
+	return externalAddress{Line1: gofakeit.Street(), Zip: gofakeit.Zip()}
+}
+
+func getRandomExternalCustomer() externalCustomer {
+	billing := getRandomExternalAddress()
+	shipping := getRandomExternalAddress()
+	return externalCustomer{
+		Name:     gofakeit.Name(),
+		Address:  getRandomExternalAddress(),
+		Billing:  &billing,
+		Previous: []externalAddress{getRandomExternalAddress(), getRandomExternalAddress()},
+		Shipping: []*externalAddress{&shipping},
+		Locations: map[string]externalAddress{
+			"work": getRandomExternalAddress(),
+		},
+	}
+}
+
+func (s *MapperSuite) assertAddressEquality(external externalAddress, internal internalAddress) {
+	require.Equal(s.T(), external.Line1, internal.Street)
+	require.Equal(s.T(), external.Zip, internal.Zip)
+}
+
+func (s *MapperSuite) assertCustomerEquality(external externalCustomer, internal internalCustomer) {
+	require.Equal(s.T(), external.Name, internal.FullName)
+	s.assertAddressEquality(external.Address, internal.Home)
+	require.NotNil(s.T(), internal.Billing)
+	s.assertAddressEquality(*external.Billing, *internal.Billing)
+	require.Len(s.T(), internal.Previous, len(external.Previous))
+	for i := range external.Previous {
+		s.assertAddressEquality(external.Previous[i], internal.Previous[i])
+	}
+	require.Len(s.T(), internal.Shipping, len(external.Shipping))
+	for i := range external.Shipping {
+		s.assertAddressEquality(*external.Shipping[i], *internal.Shipping[i])
+	}
+	require.Len(s.T(), internal.Locations, len(external.Locations))
+	for key, location := range external.Locations {
+		s.assertAddressEquality(location, internal.Locations[key])
+	}
+}
+
+func (s *MapperSuite) TestUnmarshalNestedStructs() {
+	external := getRandomExternalCustomer()
+	internal := internalCustomer{}
+	err := pkg.Convert(external, &internal)
+	require.NoError(s.T(), err)
+	s.assertCustomerEquality(external, internal)
+}
+
+func (s *MapperSuite) TestMarshalNestedStructs() {
+	external := getRandomExternalCustomer()
+	internal := internalCustomer{}
+	err := pkg.Convert(external, &internal)
+	require.NoError(s.T(), err)
+
+	roundTripped := externalCustomer{}
+	err = pkg.Convert(internal, &roundTripped)
+	require.NoError(s.T(), err)
+	s.assertCustomerEquality(roundTripped, internal)
+}
+
+func (s *MapperSuite) TestNestedPathsAreRelative() {
+	type inner struct {
+		Value string `json:"value" mapper:"deeply.nested.value"`
+	}
+	type outer struct {
+		Inner inner  `json:"inner" mapper:"wrapper.inner"`
+		Other string `json:"other" mapper:"wrapper.other"`
+	}
+	data := []byte(`{"wrapper":{"other":"outer value","inner":{"deeply":{"nested":{"value":"inner value"}}}}}`)
+	dest := outer{}
+	err := pkg.Unmarshal(data, &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "inner value", dest.Inner.Value)
+	require.Equal(s.T(), "outer value", dest.Other)
+
+	bytes, err := pkg.Marshal(dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "inner value", gjsonGet(bytes, "wrapper.inner.deeply.nested.value"))
+	require.Equal(s.T(), "outer value", gjsonGet(bytes, "wrapper.other"))
+}
+
+func (s *MapperSuite) TestNilNestedValues() {
+	external := externalCustomer{Name: gofakeit.Name()}
+	internal := internalCustomer{}
+	err := pkg.Convert(external, &internal)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), external.Name, internal.FullName)
+	require.Nil(s.T(), internal.Billing)
+	require.Nil(s.T(), internal.Previous)
+	require.Nil(s.T(), internal.Shipping)
+	require.Nil(s.T(), internal.Locations)
+}
+
+func (s *MapperSuite) TestRecursiveNestedStructs() {
+	type node struct {
+		Label    string  `json:"label" mapper:"name"`
+		Children []*node `json:"children"`
+	}
+	data := []byte(`{"name":"root","children":[{"name":"child","children":[{"name":"grandchild"}]}]}`)
+	dest := node{}
+	err := pkg.Unmarshal(data, &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "root", dest.Label)
+	require.Len(s.T(), dest.Children, 1)
+	require.Equal(s.T(), "child", dest.Children[0].Label)
+	require.Len(s.T(), dest.Children[0].Children, 1)
+	require.Equal(s.T(), "grandchild", dest.Children[0].Children[0].Label)
+}
+
+func (s *MapperSuite) TestStructsInInterfaces() {
+	address := internalAddress{Street: "Main St", Zip: "12345"}
+	mapped := `{"street":"Main St","zip":"12345","line_1":"Main St","postal_code":"12345"}`
+
+	data, err := pkg.Marshal([]any{address, &address, "plain", nil})
+	require.NoError(s.T(), err)
+	require.JSONEq(s.T(), `[`+mapped+`,`+mapped+`,"plain",null]`, string(data))
+
+	data, err = pkg.Marshal(map[string]any{"home": address, "n": 1})
+	require.NoError(s.T(), err)
+	require.JSONEq(s.T(), `{"home":`+mapped+`,"n":1}`, string(data))
+
+	type holder struct {
+		Value any `json:"value"`
+	}
+	data, err = pkg.Marshal(holder{Value: address})
+	require.NoError(s.T(), err)
+	require.JSONEq(s.T(), `{"value":`+mapped+`}`, string(data))
+
+	// a pointer inside the interface is decoded into like encoding/json does, anything else is replaced
+	target := &internalAddress{}
+	decoded := holder{Value: target}
+	require.NoError(s.T(), pkg.Unmarshal([]byte(`{"value":{"line_1":"Elm St"}}`), &decoded))
+	require.Same(s.T(), target, decoded.Value)
+	require.Equal(s.T(), "Elm St", target.Street)
+
+	decoded = holder{Value: address}
+	require.NoError(s.T(), pkg.Unmarshal([]byte(`{"value":{"line_1":"Elm St"}}`), &decoded))
+	require.Equal(s.T(), map[string]any{"line_1": "Elm St"}, decoded.Value)
+}
+
+func (s *MapperSuite) TestNestedKeysMatchIgnoringCase() {
+	type person struct {
+		Home internalAddress `json:"home"`
+	}
+	dest := person{}
+	require.NoError(s.T(), pkg.Unmarshal([]byte(`{"HOME":{"line_1":"x","postal_code":"1"}}`), &dest))
+	require.Equal(s.T(), internalAddress{Street: "x", Zip: "1"}, dest.Home)
+
+	// like encoding/json, a key that is exactly another field's json key belongs to that field
+	type claimed struct {
+		Home  internalAddress `json:"home"`
+		Upper externalAddress `json:"HOME"`
+	}
+	claimedDest := claimed{}
+	require.NoError(s.T(), pkg.Unmarshal([]byte(`{"HOME":{"line_1":"x"}}`), &claimedDest))
+	require.Equal(s.T(), internalAddress{}, claimedDest.Home)
+	require.Equal(s.T(), "x", claimedDest.Upper.Line1)
+}
+
+func (s *MapperSuite) TestUnmarshalRejectsInvalidJSON() {
+	type outer struct {
+		Ins []internalAddress `json:"ins"`
+	}
+	err := pkg.Unmarshal([]byte(`{"ins":[{"zip":"1"},]}`), &outer{})
+	require.EqualError(s.T(), err, "invalid character ']' looking for beginning of value")
+
+	err = pkg.Unmarshal([]byte(`{"a":{"line_1":"x"}`), &map[string]internalAddress{})
+	require.EqualError(s.T(), err, "unexpected end of JSON input")
+
+	err = pkg.DecodeEach(pkg.NewDecoder(strings.NewReader(`[{"line_1":"x"},{"line_1":]`)), func(internalAddress) error {
+		return nil
+	})
+	require.Error(s.T(), err)
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/stretchr/testify/require"
+)
+
+type customTagged struct {
+	Name  string `json:"name" map:"person.name" map_crm:"contact.full_name"`
+	Count int    `json:"count" map:"stats.count,coerce"`
+	Other string `json:"other" mapper:"ignored.path"`
+}
+
+func (s *MapperSuite) TestWithTagName() {
+	mapper := pkg.New(pkg.WithTagName("map"))
+	data, err := mapper.Marshal(customTagged{Name: "Ann", Count: 3, Other: "x"})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "Ann", gjsonGet(data, "person.name"))
+	require.Equal(s.T(), "3", gjsonGet(data, "stats.count"))
+	require.False(s.T(), gjsonExists(data, "ignored"))
+
+	dest := customTagged{}
+	err = mapper.Unmarshal([]byte(`{"person":{"name":"Bob"},"stats":{"count":"4"}}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "Bob", dest.Name)
+	require.Equal(s.T(), 4, dest.Count)
+
+	// profiles are named after the configured tag
+	data, err = mapper.MarshalProfile("crm", customTagged{Name: "Ann"})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "Ann", gjsonGet(data, "contact.full_name"))
+
+	// the default mapper still reads the mapper tag
+	data, err = pkg.Marshal(customTagged{Other: "x"})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "x", gjsonGet(data, "ignored.path"))
+}
+
+type documentKeyed struct {
+	UserName string               `json:"userName" doc:"user_name"`
+	Email    string               `json:"email" doc:"email_address" mapper:"contact.email"`
+	Age      int                  `json:"age" doc:"age_years" mapper:",coerce"`
+	Plain    string               `json:"plain"`
+	Child    documentKeyedChild   `json:"child" doc:"child_record"`
+	Children []documentKeyedChild `json:"children"`
+}
+
+type documentKeyedChild struct {
+	Label string `json:"label" doc:"child_label"`
+}
+
+func (s *MapperSuite) TestWithJSONTagName() {
+	mapper := pkg.New(pkg.WithJSONTagName("doc"))
+	source := documentKeyed{
+		UserName: "ann", Email: "ann@example.com", Age: 30, Plain: "p",
+		Child: documentKeyedChild{Label: "one"}, Children: []documentKeyedChild{{Label: "two"}},
+	}
+	data, err := mapper.Marshal(source)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "ann", gjsonGet(data, "user_name"))
+	require.False(s.T(), gjsonExists(data, "userName"))
+	require.Equal(s.T(), "ann@example.com", gjsonGet(data, "contact.email"))
+	require.Equal(s.T(), "ann@example.com", gjsonGet(data, "email_address"))
+	require.Equal(s.T(), "30", gjsonGet(data, "age_years"))
+	require.Equal(s.T(), "p", gjsonGet(data, "plain"))
+	require.Equal(s.T(), "one", gjsonGet(data, "child_record.child_label"))
+	require.Equal(s.T(), "two", gjsonGet(data, "children.0.child_label"))
+
+	dest := documentKeyed{}
+	err = mapper.Unmarshal(data, &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), source, dest)
+
+	// json keys aren't read for fields that have a document key
+	dest = documentKeyed{}
+	err = mapper.Unmarshal([]byte(`{"userName":"bob","age_years":"41"}`), &dest)
+	require.NoError(s.T(), err)
+	require.Empty(s.T(), dest.UserName)
+	require.Equal(s.T(), 41, dest.Age)
+}
+
+func (s *MapperSuite) TestWithDefaultCoercion() {
+	type loose struct {
+		Count  int  `json:"count" mapper:"stats.count"`
+		Active bool `json:"active" mapper:"stats.active"`
+	}
+	data := []byte(`{"stats":{"count":"12","active":"true"}}`)
+	require.Error(s.T(), pkg.Unmarshal(data, &loose{}))
+
+	dest := loose{}
+	err := pkg.New(pkg.WithDefaultCoercion()).Unmarshal(data, &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 12, dest.Count)
+	require.True(s.T(), dest.Active)
+}
+
+func (s *MapperSuite) TestWithCaseInsensitivePaths() {
+	type contact struct {
+		Email string `json:"email" mapper:"User.Contact.Email,required"`
+		Phone string `json:"phone" mapper:"user.phones.0"`
+		Dot   string `json:"dot" mapper:"user.dotted\\.key"`
+	}
+	data := []byte(`{"user":{"contact":{"EMAIL":"a@example.com"},"Phones":["555"],"Dotted.Key":"d"}}`)
+	require.Error(s.T(), pkg.Unmarshal(data, &contact{}))
+
+	dest := contact{}
+	err := pkg.New(pkg.WithCaseInsensitivePaths()).Unmarshal(data, &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "a@example.com", dest.Email)
+	require.Equal(s.T(), "555", dest.Phone)
+	require.Equal(s.T(), "d", dest.Dot)
+}
+
+func (s *MapperSuite) TestCaseInsensitivePathsPreferExactMatch() {
+	type named struct {
+		Name string `json:"name" mapper:"Name"`
+	}
+	dest := named{}
+	err := pkg.New(pkg.WithCaseInsensitivePaths()).Unmarshal([]byte(`{"name":"lower","Name":"exact"}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "exact", dest.Name)
+}
@@ -0,0 +1,110 @@
+package test
+
+import (
+	"errors"
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/joomcode/errorx"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+type queriedProfile struct {
+	Primary  string   `json:"primary" mapper:"emails.#(type==\"primary\").value,readonly"`
+	Work     []string `json:"work" mapper:"emails.#(type==\"work\")#.value,readonly"`
+	Count    int      `json:"count" mapper:"emails.#,readonly"`
+	Latest   string   `json:"latest" mapper:"emails.-1.value"`
+	Reversed []string `json:"reversed" mapper:"tags.@reverse,readonly"`
+	Keys     []string `json:"keys" mapper:"settings.@keys,readonly"`
+	Wild     string   `json:"wild" mapper:"nick*,readonly"`
+}
+
+func (s *MapperSuite) TestReadOnlyPathForms() {
+	data := []byte(`{"emails":[
+		{"type":"primary","value":"a@example.com"},
+		{"type":"work","value":"b@example.com"},
+		{"type":"work","value":"c@example.com"}
+	],"tags":["x","y","z"],"settings":{"theme":"dark","lang":"en"},"nickname":"ace"}`)
+	dest := queriedProfile{}
+	err := pkg.Unmarshal(data, &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "a@example.com", dest.Primary)
+	require.Equal(s.T(), []string{"b@example.com", "c@example.com"}, dest.Work)
+	require.Equal(s.T(), 3, dest.Count)
+	require.Equal(s.T(), "c@example.com", dest.Latest)
+	require.Equal(s.T(), []string{"z", "y", "x"}, dest.Reversed)
+	require.Equal(s.T(), []string{"theme", "lang"}, dest.Keys)
+	require.Equal(s.T(), "ace", dest.Wild)
+}
+
+func (s *MapperSuite) TestAppendPath() {
+	type entry struct {
+		Note string `json:"note" mapper:"log.-1.message"`
+		Tag  string `json:"tag" mapper:"tags.-1"`
+	}
+	data, err := pkg.Marshal(entry{Note: "hello", Tag: "t"})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), `[{"message":"hello"}]`, gjson.GetBytes(data, "log").Raw)
+	require.Equal(s.T(), `["t"]`, gjson.GetBytes(data, "tags").Raw)
+
+	dest := entry{}
+	err = pkg.Unmarshal([]byte(`{"log":[{"message":"first"},{"message":"last"}],"tags":["a","b"]}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "last", dest.Note)
+	require.Equal(s.T(), "b", dest.Tag)
+}
+
+func (s *MapperSuite) TestReadOnlyPathCantBeWritten() {
+	type primaryEmail struct {
+		Email string `json:"email" mapper:"emails.#(type==\"primary\").value"`
+	}
+	dest := primaryEmail{}
+	err := pkg.Unmarshal([]byte(`{"emails":[{"type":"primary","value":"a@example.com"}]}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "a@example.com", dest.Email)
+
+	_, err = pkg.Marshal(dest)
+	require.Error(s.T(), err)
+	require.True(s.T(), errorx.IsOfType(err, pkg.InvalidPath))
+	var mappingErr *pkg.MappingError
+	require.True(s.T(), errors.As(err, &mappingErr))
+	require.Equal(s.T(), "Email", mappingErr.Field)
+	require.Contains(s.T(), err.Error(), "can only be read")
+}
+
+func (s *MapperSuite) TestReadOnlyPathWithOutPath() {
+	type primaryEmail struct {
+		Email string `json:"email" mapper:"in=emails.#(type==\"primary\").value,out=email_address"`
+	}
+	data, err := pkg.Marshal(primaryEmail{Email: "a@example.com"})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "a@example.com", gjsonGet(data, "email_address"))
+}
+
+func (s *MapperSuite) TestInvalidPaths() {
+	type unbalanced struct {
+		Value string `json:"value" mapper:"items.#(type==\"a\".value"`
+	}
+	type emptyKey struct {
+		Value string `json:"value" mapper:"items..value"`
+	}
+	type unknownModifier struct {
+		Value string `json:"value" mapper:"items.@nope"`
+	}
+	for _, dest := range []any{&unbalanced{}, &emptyKey{}, &unknownModifier{}} {
+		err := pkg.Unmarshal([]byte(`{"items":[]}`), dest)
+		require.Error(s.T(), err)
+		require.True(s.T(), errorx.IsOfType(err, pkg.InvalidPath), err.Error())
+	}
+}
+
+func (s *MapperSuite) TestDefaultPathIsTheKey() {
+	type keyed struct {
+		Dotted int `json:"a.b" mapper:",coerce"`
+		At     int `json:"@type" mapper:",coerce"`
+	}
+	dest := keyed{}
+	err := pkg.Unmarshal([]byte(`{"a.b":"1","@type":"2","a":{"b":"3"}}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 1, dest.Dotted)
+	require.Equal(s.T(), 2, dest.At)
+}
@@ -0,0 +1,79 @@
+package test
+
+import (
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/stretchr/testify/require"
+)
+
+type profiledAddress struct {
+	Street string `json:"street" mapper:"Street__c" mapper_stripe:"address.line1"`
+	Zip    string `json:"zip" mapper:"Zip__c" mapper_stripe:"address.postal_code"`
+	Notes  string `json:"notes" mapper:"Notes__c"`
+}
+
+type profiledCustomer struct {
+	Name    string          `json:"name" mapper:"Name" mapper_stripe:"name"`
+	Address profiledAddress `json:"address" mapper:"Address__r" mapper_stripe:"billing"`
+}
+
+func (s *MapperSuite) TestProfilesMarshal() {
+	customer := profiledCustomer{Name: "Ann", Address: profiledAddress{Street: "1 Main St", Zip: "12345", Notes: "gate"}}
+
+	data, err := pkg.Marshal(customer)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "12345", gjsonGet(data, "Address__r.Zip__c"))
+	require.False(s.T(), gjsonExists(data, "billing"))
+
+	data, err = pkg.MarshalProfile("stripe", customer)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "Ann", gjsonGet(data, "name"))
+	require.Equal(s.T(), "1 Main St", gjsonGet(data, "billing.address.line1"))
+	require.Equal(s.T(), "12345", gjsonGet(data, "billing.address.postal_code"))
+	// fields without a tag for the profile aren't mapped
+	require.Equal(s.T(), "gate", gjsonGet(data, "billing.notes"))
+	require.False(s.T(), gjsonExists(data, "billing.Notes__c"))
+	require.False(s.T(), gjsonExists(data, "Address__r"))
+}
+
+func (s *MapperSuite) TestProfilesUnmarshal() {
+	dest := profiledCustomer{}
+	err := pkg.UnmarshalProfile("stripe", []byte(`{"name":"Ann","billing":{"address":{"line1":"1 Main St","postal_code":"12345"}}}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "Ann", dest.Name)
+	require.Equal(s.T(), "1 Main St", dest.Address.Street)
+	require.Equal(s.T(), "12345", dest.Address.Zip)
+
+	dest = profiledCustomer{}
+	err = pkg.Unmarshal([]byte(`{"Name":"Bob","Address__r":{"Zip__c":"54321"}}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "Bob", dest.Name)
+	require.Equal(s.T(), "54321", dest.Address.Zip)
+}
+
+func (s *MapperSuite) TestConvertProfile() {
+	type stripeCustomer struct {
+		Name    string `json:"name"`
+		Billing struct {
+			Address struct {
+				PostalCode string `json:"postal_code"`
+			} `json:"address"`
+		} `json:"billing"`
+	}
+	source := stripeCustomer{Name: "Ann"}
+	source.Billing.Address.PostalCode = "12345"
+
+	dest := profiledCustomer{}
+	err := pkg.ConvertProfile("stripe", source, &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "Ann", dest.Name)
+	require.Equal(s.T(), "12345", dest.Address.Zip)
+}
+
+func (s *MapperSuite) TestProfileSharesMapperOptions() {
+	type strictCounts struct {
+		Count int `json:"count" mapper_legacy:"legacy.count,coerce"`
+	}
+	mapper := pkg.New(pkg.WithStrictCoercion())
+	err := mapper.UnmarshalProfile("legacy", []byte(`{"legacy":{"count":1.5}}`), &strictCounts{})
+	require.Error(s.T(), err)
+}
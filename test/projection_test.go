@@ -0,0 +1,78 @@
+package test
+
+import (
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/joomcode/errorx"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	"time"
+)
+
+type projectedOrder struct {
+	IDs      []string    `json:"ids" mapper:"items.#.id"`
+	Counts   []int       `json:"counts" mapper:"items.#.qty,coerce"`
+	Prices   []*float64  `json:"prices" mapper:"items.#.price.amount,strict"`
+	Shipped  []time.Time `json:"shipped" mapper:"items.#.shipped,coerce,layout=DateOnly"`
+	Featured []string    `json:"featured" mapper:"items.#(featured==true)#.id,readonly"`
+}
+
+func (s *MapperSuite) TestProjectionUnmarshal() {
+	data := []byte(`{"items":[
+		{"id":"a","qty":"1","price":{"amount":"1.5"},"shipped":"2023-03-01","featured":true},
+		{"id":"b","qty":2,"price":{"amount":2},"shipped":"2023-03-02"}
+	]}`)
+	dest := projectedOrder{}
+	err := pkg.Unmarshal(data, &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []string{"a", "b"}, dest.IDs)
+	require.Equal(s.T(), []int{1, 2}, dest.Counts)
+	require.Len(s.T(), dest.Prices, 2)
+	require.Equal(s.T(), 1.5, *dest.Prices[0])
+	require.Equal(s.T(), 2.0, *dest.Prices[1])
+	require.Equal(s.T(), time.Date(2023, 3, 2, 0, 0, 0, 0, time.UTC), dest.Shipped[1])
+	require.Equal(s.T(), []string{"a"}, dest.Featured)
+}
+
+func (s *MapperSuite) TestProjectionMarshalScatters() {
+	one, two := 1.5, 2.0
+	source := projectedOrder{
+		IDs:     []string{"a", "b"},
+		Counts:  []int{1, 2},
+		Prices:  []*float64{&one, &two},
+		Shipped: []time.Time{time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 3, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	data, err := pkg.Marshal(source)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), `[{"id":"a","qty":1,"price":{"amount":1.5},"shipped":"2023-03-01"},{"id":"b","qty":2,"price":{"amount":2},"shipped":"2023-03-02"}]`,
+		gjson.GetBytes(data, "items|@ugly").Raw)
+
+	dest := projectedOrder{}
+	err = pkg.Unmarshal(data, &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), source.IDs, dest.IDs)
+	require.Equal(s.T(), source.Counts, dest.Counts)
+	require.Equal(s.T(), source.Shipped, dest.Shipped)
+}
+
+func (s *MapperSuite) TestProjectionMarshalEmptyAndNil() {
+	type ids struct {
+		IDs []string `json:"ids" mapper:"items.#.id"`
+	}
+	data, err := pkg.Marshal(ids{IDs: []string{}})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "[]", gjson.GetBytes(data, "items").Raw)
+
+	data, err = pkg.Marshal(ids{})
+	require.NoError(s.T(), err)
+	require.False(s.T(), gjsonExists(data, "items"))
+}
+
+func (s *MapperSuite) TestProjectionElementErrors() {
+	type counts struct {
+		Counts []int `json:"counts" mapper:"items.#.qty,strict"`
+	}
+	err := pkg.Unmarshal([]byte(`{"items":[{"qty":1},{"qty":"many"}]}`), &counts{})
+	require.Error(s.T(), err)
+	require.True(s.T(), errorx.IsOfType(err, pkg.CoercionFailed))
+	require.Contains(s.T(), err.Error(), "element 1")
+}
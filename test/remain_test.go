@@ -0,0 +1,104 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+type driftingContact struct {
+	Email string         `json:"email" mapper:"contact.email"`
+	Name  string         `json:"name"`
+	Rest  map[string]any `json:"rest" mapper:",remain"`
+}
+
+const driftingPayload = `{"contact":{"email":"a@example.com","phone":"555"},"name":"Ann","rest":"mine","tier":"gold","tags":["a","b"]}`
+
+func (s *MapperSuite) TestRemainCollectsUnreadKeys() {
+	dest := driftingContact{}
+	err := pkg.Unmarshal([]byte(driftingPayload), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "a@example.com", dest.Email)
+	require.Equal(s.T(), "Ann", dest.Name)
+	require.Equal(s.T(), map[string]any{
+		"contact": map[string]any{"phone": "555"},
+		"rest":    "mine",
+		"tier":    "gold",
+		"tags":    []any{"a", "b"},
+	}, dest.Rest)
+}
+
+func (s *MapperSuite) TestRemainRoundTrip() {
+	dest := driftingContact{}
+	require.NoError(s.T(), pkg.Unmarshal([]byte(driftingPayload), &dest))
+	data, err := pkg.Marshal(dest)
+	require.NoError(s.T(), err)
+	// the mapped field is also written at its json key, as always
+	require.JSONEq(s.T(), driftingPayload, string(must(sjson.DeleteBytes(data, "email"))))
+}
+
+func must(data []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func (s *MapperSuite) TestRemainDoesntOverrideFields() {
+	data, err := pkg.Marshal(driftingContact{
+		Email: "a@example.com",
+		Name:  "Ann",
+		Rest:  map[string]any{"name": "other", "contact": map[string]any{"email": "other", "phone": "555"}},
+	})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "Ann", gjsonGet(data, "name"))
+	require.Equal(s.T(), "a@example.com", gjsonGet(data, "contact.email"))
+	require.Equal(s.T(), "555", gjsonGet(data, "contact.phone"))
+}
+
+func (s *MapperSuite) TestRemainEmpty() {
+	dest := driftingContact{}
+	require.NoError(s.T(), pkg.Unmarshal([]byte(`{"name":"Ann"}`), &dest))
+	require.Nil(s.T(), dest.Rest)
+
+	data, err := pkg.Marshal(dest)
+	require.NoError(s.T(), err)
+	require.False(s.T(), gjsonExists(data, "rest"))
+}
+
+func (s *MapperSuite) TestRemainRawMessageInNestedStruct() {
+	type inner struct {
+		ID   string          `json:"id" mapper:"ref.id"`
+		Rest json.RawMessage `json:"rest" mapper:",remain"`
+	}
+	type outer struct {
+		Inner inner `json:"inner" mapper:"payload"`
+	}
+	payload := `{"payload":{"ref":{"id":"1","kind":"x"},"flag":true}}`
+	dest := outer{}
+	require.NoError(s.T(), pkg.Unmarshal([]byte(payload), &dest))
+	require.Equal(s.T(), "1", dest.Inner.ID)
+	require.JSONEq(s.T(), `{"ref":{"kind":"x"},"flag":true}`, string(dest.Inner.Rest))
+
+	data, err := pkg.Marshal(dest)
+	require.NoError(s.T(), err)
+	require.JSONEq(s.T(), `{"ref":{"id":"1","kind":"x"},"flag":true,"id":"1"}`, gjson.GetBytes(data, "payload").Raw)
+}
+
+func (s *MapperSuite) TestRemainCountsAsKnown() {
+	mapper := pkg.New(pkg.WithUnknownFields(pkg.RejectUnknownFields, nil))
+	dest := driftingContact{}
+	require.NoError(s.T(), mapper.Unmarshal([]byte(driftingPayload), &dest))
+	require.Equal(s.T(), "gold", dest.Rest["tier"])
+}
+
+func (s *MapperSuite) TestRemainNeedsAnObjectType() {
+	type badRemain struct {
+		Rest []string `json:"rest" mapper:",remain"`
+	}
+	err := pkg.Unmarshal([]byte(`{"other":1}`), &badRemain{})
+	require.Error(s.T(), err)
+	require.Contains(s.T(), err.Error(), "must be a map with string keys or a json.RawMessage")
+}
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"errors"
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/joomcode/errorx"
+	"github.com/stretchr/testify/require"
+)
+
+type requiredOrder struct {
+	Number string `json:"number" mapper:"order.number,required"`
+	Zip    string `json:"zip" mapper:"customer.address.zip,required"`
+	Note   string `json:"note" mapper:"order.note"`
+}
+
+func (s *MapperSuite) TestRequiredPathsPresent() {
+	dest := requiredOrder{}
+	err := pkg.Unmarshal([]byte(`{"order":{"number":"A-1"},"customer":{"address":{"zip":"12345"}}}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "A-1", dest.Number)
+	require.Equal(s.T(), "12345", dest.Zip)
+}
+
+func (s *MapperSuite) TestRequiredPathsMissing() {
+	dest := requiredOrder{}
+	err := pkg.Unmarshal([]byte(`{"order":{"number":null,"note":"leave at the door"},"customer":{"address":{}}}`), &dest)
+	require.Error(s.T(), err)
+	require.Contains(s.T(), err.Error(), "required path order.number is missing")
+	require.Contains(s.T(), err.Error(), "required path customer.address.zip is missing")
+
+	var many pkg.MappingErrors
+	require.True(s.T(), errors.As(err, &many))
+	require.Len(s.T(), many, 2)
+	for _, mappingErr := range many {
+		require.True(s.T(), errorx.IsOfType(mappingErr, pkg.RequiredFieldMissing))
+	}
+	require.Equal(s.T(), "Number", many[0].Field)
+	require.Equal(s.T(), "Zip", many[1].Field)
+	// nothing is decoded when required data is missing
+	require.Equal(s.T(), "", dest.Note)
+}
+
+func (s *MapperSuite) TestRequiredPathsInSlices() {
+	data := []byte(`[
+		{"order":{"number":"A-1"},"customer":{"address":{"zip":"12345"}}},
+		{"order":{},"customer":{"address":{"zip":"12345"}}},
+		{"order":{"number":"A-3"}}
+	]`)
+	orders := []requiredOrder{}
+	err := pkg.Unmarshal(data, &orders)
+	require.Error(s.T(), err)
+
+	var many pkg.MappingErrors
+	require.True(s.T(), errors.As(err, &many))
+	require.Len(s.T(), many, 2)
+	require.Equal(s.T(), "1.order.number", many[0].JSONPath)
+	require.Equal(s.T(), 1, many[0].Index)
+	require.Equal(s.T(), "2.customer.address.zip", many[1].JSONPath)
+	require.Equal(s.T(), 2, many[1].Index)
+}
+
+func (s *MapperSuite) TestRequiredPathsInNestedStructs() {
+	type wrapper struct {
+		Orders []requiredOrder `json:"orders" mapper:"data.orders"`
+	}
+	err := pkg.Unmarshal([]byte(`{"data":{"orders":[{"order":{"number":"A-1"}}]}}`), &wrapper{})
+	require.Error(s.T(), err)
+	var mappingErr *pkg.MappingError
+	require.True(s.T(), errors.As(err, &mappingErr))
+	require.Equal(s.T(), "Orders[0].Zip", mappingErr.Field)
+	require.Equal(s.T(), "data.orders.0.customer.address.zip", mappingErr.JSONPath)
+}
+
+func (s *MapperSuite) TestRequiredWithConvert() {
+	type source struct {
+		Number string `json:"number"`
+	}
+	type dest struct {
+		Number string `json:"number" mapper:"number,required"`
+		Zip    string `json:"zip" mapper:"zip,required"`
+	}
+	err := pkg.Convert(source{Number: "A-1"}, &dest{})
+	require.Error(s.T(), err)
+	require.Contains(s.T(), err.Error(), "required path zip is missing")
+}
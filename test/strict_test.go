@@ -0,0 +1,97 @@
+package test
+
+import (
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/joomcode/errorx"
+	"github.com/stretchr/testify/require"
+)
+
+type strictStruct struct {
+	AnInt   int     `json:"an_int" mapper:"value,coerce,strict"`
+	AnInt16 int16   `json:"an_int_16" mapper:"value,strict"`
+	AUint8  uint8   `json:"a_uint_8" mapper:"value,strict"`
+	AFloat  float32 `json:"a_float" mapper:"value,strict"`
+	ABool   bool    `json:"a_bool" mapper:"flag,strict"`
+}
+
+func (s *MapperSuite) TestStrictCoercionAcceptsLosslessValues() {
+	dest := strictStruct{}
+	err := pkg.Unmarshal([]byte(`{"value":"200","flag":"true"}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 200, dest.AnInt)
+	require.Equal(s.T(), int16(200), dest.AnInt16)
+	require.Equal(s.T(), uint8(200), dest.AUint8)
+	require.Equal(s.T(), float32(200), dest.AFloat)
+	require.True(s.T(), dest.ABool)
+
+	dest = strictStruct{}
+	err = pkg.Unmarshal([]byte(`{"value":2e2,"flag":0}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 200, dest.AnInt)
+	require.False(s.T(), dest.ABool)
+}
+
+func (s *MapperSuite) TestStrictCoercionRejectsLossyValues() {
+	type strictInt struct {
+		Value int `json:"value" mapper:"value,coerce,strict"`
+	}
+	type strictInt16 struct {
+		Value int16 `json:"value" mapper:"value,coerce,strict"`
+	}
+	type strictUint struct {
+		Value uint `json:"value" mapper:"value,coerce,strict"`
+	}
+	type strictBool struct {
+		Value bool `json:"value" mapper:"value,coerce,strict"`
+	}
+	cases := []struct {
+		data    string
+		dest    any
+		message string
+	}{
+		{`{"value":"abc"}`, &strictInt{}, "cannot coerce"},
+		{`{"value":3.9}`, &strictInt{}, "would be truncated"},
+		{`{"value":70000}`, &strictInt16{}, "overflows int16"},
+		{`{"value":-1}`, &strictUint{}, "negative"},
+		{`{"value":"yes please"}`, &strictBool{}, "cannot coerce"},
+		{`{"value":{"nested":true}}`, &strictInt{}, "cannot coerce"},
+	}
+	for _, testCase := range cases {
+		err := pkg.Unmarshal([]byte(testCase.data), testCase.dest)
+		require.Error(s.T(), err, testCase.data)
+		require.True(s.T(), errorx.IsOfType(err, pkg.CoercionFailed), testCase.data)
+		require.Contains(s.T(), err.Error(), testCase.message, testCase.data)
+	}
+}
+
+func (s *MapperSuite) TestStrictCoercionLeavesMissingValues() {
+	dest := strictStruct{AnInt: 5}
+	err := pkg.Unmarshal([]byte(`{"flag":true}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 5, dest.AnInt)
+}
+
+func (s *MapperSuite) TestLenientCoercionIsTheDefault() {
+	type lenient struct {
+		Value int16 `json:"value" mapper:"value,coerce"`
+	}
+	dest := lenient{}
+	err := pkg.Unmarshal([]byte(`{"value":3.9}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int16(3), dest.Value)
+}
+
+func (s *MapperSuite) TestStrictCoercionOption() {
+	type lenient struct {
+		Value int16 `json:"value" mapper:"value,coerce"`
+	}
+	mapper := pkg.New(pkg.WithStrictCoercion())
+	err := mapper.Unmarshal([]byte(`{"value":70000}`), &lenient{})
+	require.Error(s.T(), err)
+	require.True(s.T(), errorx.IsOfType(err, pkg.CoercionFailed))
+
+	dest := lenient{}
+	err = mapper.Unmarshal([]byte(`{"value":"12"}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int16(12), dest.Value)
+}
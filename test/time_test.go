@@ -0,0 +1,123 @@
+package test
+
+import (
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/stretchr/testify/require"
+	"time"
+)
+
+func (s *MapperSuite) TestCoerceEpochTimes() {
+	type dest struct {
+		Seconds     time.Time  `json:"seconds" mapper:"s,coerce"`
+		Fractional  time.Time  `json:"fractional" mapper:"f,coerce"`
+		Millis      time.Time  `json:"millis" mapper:"ms,coerce,layout=unixmilli"`
+		Micros      time.Time  `json:"micros" mapper:"us,coerce,layout=unixmicro"`
+		Nanos       *time.Time `json:"nanos" mapper:"ns,coerce,layout=unixnano"`
+		StringEpoch time.Time  `json:"string_epoch" mapper:"str,coerce"`
+	}
+	expected := time.Date(2023, 3, 17, 12, 30, 45, 0, time.UTC)
+	data := []byte(`{"s":1679056245,"f":1679056245.5,"ms":1679056245000,"us":1679056245000000,"ns":1679056245000000000,"str":"1679056245"}`)
+	theDest := dest{}
+	err := pkg.Unmarshal(data, &theDest)
+	require.NoError(s.T(), err)
+	require.True(s.T(), expected.Equal(theDest.Seconds))
+	require.True(s.T(), expected.Add(500*time.Millisecond).Equal(theDest.Fractional))
+	require.True(s.T(), expected.Equal(theDest.Millis))
+	require.True(s.T(), expected.Equal(theDest.Micros))
+	require.NotNil(s.T(), theDest.Nanos)
+	require.True(s.T(), expected.Equal(*theDest.Nanos))
+	require.True(s.T(), expected.Equal(theDest.StringEpoch))
+
+	bytes, err := pkg.Marshal(theDest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "1679056245000", gjsonGet(bytes, "ms"))
+	require.Equal(s.T(), "1679056245000000", gjsonGet(bytes, "us"))
+	require.Equal(s.T(), "1679056245000000000", gjsonGet(bytes, "ns"))
+}
+
+func (s *MapperSuite) TestCoerceTimeLayouts() {
+	type dest struct {
+		RFC3339  time.Time `json:"rfc3339" mapper:"a,coerce"`
+		DateOnly time.Time `json:"date_only" mapper:"b,coerce,layout=2006-01-02"`
+		Named    time.Time `json:"named" mapper:"c,coerce,layout=RFC1123"`
+		Local    time.Time `json:"local" mapper:"d,coerce,layout=DateTime,tz=America/New_York"`
+	}
+	data := []byte(`{"a":"2023-03-17T12:30:45Z","b":"2023-03-17","c":"Fri, 17 Mar 2023 12:30:45 UTC","d":"2023-03-17 08:30:45"}`)
+	theDest := dest{}
+	err := pkg.Unmarshal(data, &theDest)
+	require.NoError(s.T(), err)
+	expected := time.Date(2023, 3, 17, 12, 30, 45, 0, time.UTC)
+	require.True(s.T(), expected.Equal(theDest.RFC3339))
+	require.True(s.T(), time.Date(2023, 3, 17, 0, 0, 0, 0, time.UTC).Equal(theDest.DateOnly))
+	require.True(s.T(), expected.Equal(theDest.Named))
+	// the local wall clock in New York is parsed in that zone
+	require.True(s.T(), expected.Equal(theDest.Local))
+
+	bytes, err := pkg.Marshal(theDest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "2023-03-17T12:30:45Z", gjsonGet(bytes, "a"))
+	require.Equal(s.T(), "2023-03-17", gjsonGet(bytes, "b"))
+	require.Equal(s.T(), "Fri, 17 Mar 2023 12:30:45 UTC", gjsonGet(bytes, "c"))
+	require.Equal(s.T(), "2023-03-17 08:30:45", gjsonGet(bytes, "d"))
+}
+
+func (s *MapperSuite) TestCoerceDigitsOnlyLayout() {
+	type dest struct {
+		Day   time.Time `json:"day" mapper:"day,coerce,layout=20060102"`
+		Stamp time.Time `json:"stamp" mapper:"stamp,coerce,layout=20060102150405"`
+	}
+	// numbers and numeric strings are parsed with the layout, not read as epoch seconds
+	data := []byte(`{"day":"20240115","stamp":20240115083000}`)
+	theDest := dest{}
+	require.NoError(s.T(), pkg.Unmarshal(data, &theDest))
+	require.True(s.T(), time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC).Equal(theDest.Day))
+	require.True(s.T(), time.Date(2024, 1, 15, 8, 30, 0, 0, time.UTC).Equal(theDest.Stamp))
+
+	bytes, err := pkg.Marshal(theDest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "20240115", gjsonGet(bytes, "day"))
+	require.Equal(s.T(), "20240115083000", gjsonGet(bytes, "stamp"))
+
+	roundTripped := dest{}
+	require.NoError(s.T(), pkg.Unmarshal(bytes, &roundTripped))
+	require.Equal(s.T(), theDest, roundTripped)
+}
+
+func (s *MapperSuite) TestCoerceTimeErrors() {
+	type badLayout struct {
+		When time.Time `json:"when" mapper:"when,coerce,layout=2006-01-02"`
+	}
+	err := pkg.Unmarshal([]byte(`{"when":"17/03/2023"}`), &badLayout{})
+	require.Error(s.T(), err)
+
+	type badZone struct {
+		When time.Time `json:"when" mapper:"when,coerce,tz=Not/AZone"`
+	}
+	err = pkg.Unmarshal([]byte(`{"when":"2023-03-17T12:30:45Z"}`), &badZone{})
+	require.Error(s.T(), err)
+	require.Contains(s.T(), err.Error(), "unknown time zone Not/AZone")
+}
+
+func (s *MapperSuite) TestCoerceDurations() {
+	type dest struct {
+		FromString  time.Duration  `json:"from_string" mapper:"a,coerce"`
+		FromSeconds time.Duration  `json:"from_seconds" mapper:"b,coerce"`
+		AsSeconds   *time.Duration `json:"as_seconds" mapper:"c,coerce,layout=seconds"`
+	}
+	theDest := dest{}
+	err := pkg.Unmarshal([]byte(`{"a":"1h30m","b":90.5,"c":"2s"}`), &theDest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 90*time.Minute, theDest.FromString)
+	require.Equal(s.T(), 90*time.Second+500*time.Millisecond, theDest.FromSeconds)
+	require.NotNil(s.T(), theDest.AsSeconds)
+	require.Equal(s.T(), 2*time.Second, *theDest.AsSeconds)
+
+	bytes, err := pkg.Marshal(theDest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "1h30m0s", gjsonGet(bytes, "a"))
+	require.Equal(s.T(), "1m30.5s", gjsonGet(bytes, "b"))
+	require.Equal(s.T(), "2", gjsonGet(bytes, "c"))
+
+	err = pkg.Unmarshal([]byte(`{"a":"an hour"}`), &theDest)
+	require.Error(s.T(), err)
+}
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"errors"
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/joomcode/errorx"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	"strings"
+)
+
+type transformedContact struct {
+	Email   string   `json:"email" mapper:"user.email,transform=trim|lower"`
+	Tags    []string `json:"tags" mapper:"user.tags,transform=split(,)"`
+	Path    []string `json:"path" mapper:"user.path,transform=split(|)"`
+	Price   float64  `json:"price" mapper:"price_cents,transform=divide(100)"`
+	Score   float64  `json:"score" mapper:"score,transform=round(1)"`
+	Primary string   `json:"primary" mapper:"phones,transform=compact|first"`
+}
+
+func (s *MapperSuite) TestTransformsUnmarshal() {
+	dest := transformedContact{}
+	err := pkg.Unmarshal([]byte(`{"user":{"email":"  Ann@Example.COM ","tags":"a,b,c","path":"x|y"},
+		"price_cents":1999,"score":4.26,"phones":[null,"","555-1234","555-9999"]}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "ann@example.com", dest.Email)
+	require.Equal(s.T(), []string{"a", "b", "c"}, dest.Tags)
+	require.Equal(s.T(), []string{"x", "y"}, dest.Path)
+	require.Equal(s.T(), 19.99, dest.Price)
+	require.Equal(s.T(), 4.3, dest.Score)
+	require.Equal(s.T(), "555-1234", dest.Primary)
+}
+
+func (s *MapperSuite) TestTransformsMarshalUseInverses() {
+	data, err := pkg.Marshal(transformedContact{
+		Email: "Ann@Example.com", Tags: []string{"a", "b"}, Path: []string{"x", "y"}, Price: 19.99, Score: 4.26, Primary: "555",
+	})
+	require.NoError(s.T(), err)
+	// trim and lower can't be undone, the value is written as it is
+	require.Equal(s.T(), "Ann@Example.com", gjsonGet(data, "user.email"))
+	require.Equal(s.T(), "a,b", gjsonGet(data, "user.tags"))
+	require.Equal(s.T(), "x|y", gjsonGet(data, "user.path"))
+	require.Equal(s.T(), "1999", gjsonGet(data, "price_cents"))
+	require.Equal(s.T(), "4.26", gjsonGet(data, "score"))
+	require.Equal(s.T(), `["555"]`, gjson.GetBytes(data, "phones").Raw)
+}
+
+func (s *MapperSuite) TestTransformsLeaveNullsAlone() {
+	dest := transformedContact{Tags: []string{"kept"}}
+	err := pkg.Unmarshal([]byte(`{"user":{"email":null,"tags":null}}`), &dest)
+	require.NoError(s.T(), err)
+	require.Empty(s.T(), dest.Email)
+	require.Nil(s.T(), dest.Tags)
+}
+
+func (s *MapperSuite) TestTransformsWithCoercion() {
+	type quantity struct {
+		Count int `json:"count" mapper:"count,coerce,transform=trim"`
+	}
+	dest := quantity{}
+	err := pkg.Unmarshal([]byte(`{"count":" 42 "}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 42, dest.Count)
+}
+
+func (s *MapperSuite) TestTransformErrors() {
+	type broken struct {
+		Tags  []string `json:"tags" mapper:"tags,transform=split"`
+		Other string   `json:"other" mapper:"other,transform=nope"`
+	}
+	err := pkg.Unmarshal([]byte(`{"tags":5}`), &broken{})
+	require.Error(s.T(), err)
+	var mappingErr *pkg.MappingError
+	require.True(s.T(), errors.As(err, &mappingErr))
+	require.Equal(s.T(), "Tags", mappingErr.Field)
+	require.True(s.T(), errorx.IsOfType(err, pkg.TransformFailed))
+
+	err = pkg.Unmarshal([]byte(`{"other":"x"}`), &broken{})
+	require.Error(s.T(), err)
+	require.Contains(s.T(), err.Error(), "unknown transform nope")
+}
+
+func (s *MapperSuite) TestRegisteredTransforms() {
+	reverse := func(value gjson.Result, _ string) (any, error) {
+		runes := []rune(value.String())
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return string(runes), nil
+	}
+	prefix := func(value gjson.Result, arg string) (any, error) {
+		return arg + value.String(), nil
+	}
+	unprefix := func(value gjson.Result, arg string) (any, error) {
+		return strings.TrimPrefix(value.String(), arg), nil
+	}
+	pkg.RegisterTransform("test_reverse", reverse, reverse)
+
+	type coded struct {
+		Code string `json:"code" mapper:"code,transform=test_reverse|test_prefix(id-)"`
+	}
+	mapper := pkg.New(pkg.WithTransform("test_prefix", prefix, unprefix))
+	dest := coded{}
+	err := mapper.Unmarshal([]byte(`{"code":"cba"}`), &dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "id-abc", dest.Code)
+
+	data, err := mapper.Marshal(dest)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "cba", gjsonGet(data, "code"))
+
+	// transforms registered on a Mapper aren't seen by the others
+	require.Error(s.T(), pkg.Unmarshal([]byte(`{"code":"cba"}`), &coded{}))
+}
+
+func (s *MapperSuite) TestTransformsMarshalWithString() {
+	type labeled struct {
+		Label string `json:"label" mapper:"labels,string,transform=join(-)"`
+		Total int    `json:"total" mapper:"total,string,transform=first"`
+	}
+	data, err := pkg.Marshal(labeled{Label: "a-b", Total: 5})
+	require.NoError(s.T(), err)
+	// the inverses run before the value is read as a string, like the transforms do on unmarshal
+	require.True(s.T(), gjson.ValidBytes(data))
+	require.Equal(s.T(), `["a","b"]`, gjson.GetBytes(data, "labels").Raw)
+	require.Equal(s.T(), `[5]`, gjson.GetBytes(data, "total").Raw)
+
+	dest := labeled{}
+	require.NoError(s.T(), pkg.Unmarshal([]byte(`{"total":["5"]}`), &dest))
+	require.Equal(s.T(), 5, dest.Total)
+}
@@ -0,0 +1,118 @@
+package test
+
+import (
+	"errors"
+	"github.com/catalystcommunity/mapper/pkg"
+	"github.com/joomcode/errorx"
+	"github.com/stretchr/testify/require"
+	"sort"
+)
+
+type partnerItem struct {
+	Sku string `json:"sku" mapper:"product.sku"`
+	Qty int    `json:"qty"`
+}
+
+type partnerSettings struct {
+	Theme string `json:"theme"`
+}
+
+type partnerOrder struct {
+	Number   string            `json:"number" mapper:"order.number"`
+	Email    string            `json:"email" mapper:"customer.contact.email"`
+	Note     string            `json:"note"`
+	Settings partnerSettings   `json:"settings"`
+	Labels   map[string]string `json:"labels"`
+	Items    []partnerItem     `json:"items" mapper:"order.items"`
+}
+
+const partnerPayload = `{
+	"order":{"number":"A-1","placed":"today","items":[{"product":{"sku":"a","color":"red"},"qty":1,"gift":true}]},
+	"customer":{"contact":{"email":"a@example.com","phone":"555"},"name":"Ann"},
+	"NOTE":"case doesn't matter",
+	"settings":{"theme":"dark","font":"mono"},
+	"labels":{"anything":"goes"},
+	"extra":1
+}`
+
+var expectedUnknown = []string{
+	"customer.contact.phone",
+	"customer.name",
+	"extra",
+	"order.items.0.gift",
+	"order.items.0.product.color",
+	"order.placed",
+	"settings.font",
+}
+
+func (s *MapperSuite) TestUnknownFieldsIgnoredByDefault() {
+	dest := partnerOrder{}
+	require.NoError(s.T(), pkg.Unmarshal([]byte(partnerPayload), &dest))
+	require.Equal(s.T(), "case doesn't matter", dest.Note)
+}
+
+func (s *MapperSuite) TestUnknownFieldsRejected() {
+	mapper := pkg.New(pkg.WithUnknownFields(pkg.RejectUnknownFields, nil))
+	dest := partnerOrder{}
+	err := mapper.Unmarshal([]byte(partnerPayload), &dest)
+	require.Error(s.T(), err)
+
+	var many pkg.MappingErrors
+	require.True(s.T(), errors.As(err, &many))
+	paths := []string{}
+	for _, mappingErr := range many {
+		require.True(s.T(), errorx.IsOfType(mappingErr, pkg.UnknownField))
+		paths = append(paths, mappingErr.JSONPath)
+	}
+	sort.Strings(paths)
+	require.Equal(s.T(), expectedUnknown, paths)
+	// everything known is still decoded
+	require.Equal(s.T(), "A-1", dest.Number)
+	require.Equal(s.T(), "a", dest.Items[0].Sku)
+}
+
+func (s *MapperSuite) TestUnknownFieldsWarned() {
+	paths := []string{}
+	mapper := pkg.New(pkg.WithUnknownFields(pkg.WarnUnknownFields, func(path string) {
+		paths = append(paths, path)
+	}))
+	dest := partnerOrder{}
+	err := mapper.Unmarshal([]byte(partnerPayload), &dest)
+	require.NoError(s.T(), err)
+	sort.Strings(paths)
+	require.Equal(s.T(), expectedUnknown, paths)
+	require.Equal(s.T(), "A-1", dest.Number)
+}
+
+func (s *MapperSuite) TestUnknownFieldsKeepOtherErrors() {
+	paths := []string{}
+	mapper := pkg.New(pkg.WithUnknownFields(pkg.WarnUnknownFields, func(path string) {
+		paths = append(paths, path)
+	}))
+	err := mapper.Unmarshal([]byte(`{"order":{"number":1},"extra":true}`), &partnerOrder{})
+	require.Error(s.T(), err)
+	require.False(s.T(), errorx.IsOfType(err, pkg.UnknownField))
+}
+
+func (s *MapperSuite) TestUnknownFieldsInPlainTypes() {
+	mapper := pkg.New(pkg.WithUnknownFields(pkg.RejectUnknownFields, nil))
+	err := mapper.Unmarshal([]byte(`[{"theme":"dark"},{"theme":"light","size":2}]`), &[]partnerSettings{})
+	require.Error(s.T(), err)
+	var mappingErr *pkg.MappingError
+	require.True(s.T(), errors.As(err, &mappingErr))
+	require.Equal(s.T(), "1.size", mappingErr.JSONPath)
+	require.True(s.T(), errorx.IsOfType(mappingErr.Err, pkg.UnknownField))
+}
+
+func (s *MapperSuite) TestUnknownFieldsWithQueries() {
+	type primary struct {
+		Email string   `json:"email" mapper:"emails.#(type==\"primary\").value,readonly"`
+		Kinds []string `json:"kinds" mapper:"emails.#.type,readonly"`
+	}
+	mapper := pkg.New(pkg.WithUnknownFields(pkg.RejectUnknownFields, nil))
+	err := mapper.Unmarshal([]byte(`{"emails":[{"type":"primary","value":"a"},{"type":"work","value":"b"}]}`), &primary{})
+	require.Error(s.T(), err)
+	var mappingErr *pkg.MappingError
+	require.True(s.T(), errors.As(err, &mappingErr))
+	require.Equal(s.T(), "emails.1.value", mappingErr.JSONPath)
+}